@@ -0,0 +1,237 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+var DefaultPercentiles = []float64{5, 25, 50, 75, 95}
+
+type SeriesPoint struct {
+	Period string
+	Date   time.Time
+	Value  float64
+}
+
+type Drawdown struct {
+	Depth  float64
+	Peak   Exchange
+	Trough Exchange
+}
+
+type CurrencyAnalytics struct {
+	Currency             string
+	Count                int
+	Average              float64
+	Highest              Exchange
+	Lowest               Exchange
+	LogReturns           []SeriesPoint
+	RollingStdDev        []SeriesPoint
+	AnnualizedVolatility float64
+	MaxDrawdown          Drawdown
+	Percentiles          map[float64]float64
+}
+
+// currencySeries returns the rows of one currency ordered by date.
+func currencySeries(data []Exchange, currency string) []Exchange {
+	series := ExchangeQuery{Currency: currency}.Filter(data)
+	sort.SliceStable(series, func(i, j int) bool {
+		return series[i].Date.Before(series[j].Date)
+	})
+	return series
+}
+
+func LogReturns(series []Exchange) []SeriesPoint {
+	if len(series) < 2 {
+		return nil
+	}
+	returns := make([]SeriesPoint, 0, len(series)-1)
+	for i := 1; i < len(series); i++ {
+		returns = append(returns, SeriesPoint{
+			Period: series[i].Period,
+			Date:   series[i].Date,
+			Value:  math.Log(series[i].Rate / series[i-1].Rate),
+		})
+	}
+	return returns
+}
+
+func RollingStdDev(points []SeriesPoint, window int) []SeriesPoint {
+	if window < 2 || len(points) < window {
+		return nil
+	}
+	result := make([]SeriesPoint, 0, len(points)-window+1)
+	for i := window - 1; i < len(points); i++ {
+		values := make([]float64, window)
+		for j := range values {
+			values[j] = points[i-window+1+j].Value
+		}
+		result = append(result, SeriesPoint{
+			Period: points[i].Period,
+			Date:   points[i].Date,
+			Value:  stdDev(values),
+		})
+	}
+	return result
+}
+
+// AnnualizedVolatility scales the standard deviation of the returns by the number of
+// observations per year, estimated from the dates spanned by the series.
+func AnnualizedVolatility(returns []SeriesPoint) float64 {
+	if len(returns) < 2 {
+		return 0
+	}
+	values := make([]float64, len(returns))
+	for i, r := range returns {
+		values[i] = r.Value
+	}
+	years := returns[len(returns)-1].Date.Sub(returns[0].Date).Hours() / 24 / 365.25
+	if years <= 0 {
+		return 0
+	}
+	perYear := float64(len(returns)-1) / years
+	return stdDev(values) * math.Sqrt(perYear)
+}
+
+func MaxDrawdown(series []Exchange) Drawdown {
+	var result Drawdown
+	if len(series) == 0 {
+		return result
+	}
+	peak := series[0]
+	for _, exchange := range series {
+		if exchange.Rate > peak.Rate {
+			peak = exchange
+		}
+		depth := (peak.Rate - exchange.Rate) / peak.Rate
+		if depth > result.Depth {
+			result = Drawdown{Depth: depth, Peak: peak, Trough: exchange}
+		}
+	}
+	return result
+}
+
+// Percentile returns the p-th percentile (0-100) of values using linear interpolation.
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	return percentileSorted(sorted, p)
+}
+
+func percentileSorted(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower < 0 {
+		return sorted[0]
+	}
+	if upper >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func stdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := mean(values)
+	var sum float64
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+func AnalyzeCurrency(data []Exchange, currency string, window int) (CurrencyAnalytics, error) {
+	series := currencySeries(data, currency)
+	if len(series) == 0 {
+		return CurrencyAnalytics{}, &UnknownCurrencyError{Currency: currency}
+	}
+
+	rates := make([]float64, len(series))
+	for i, exchange := range series {
+		rates[i] = exchange.Rate
+	}
+	sort.Float64s(rates)
+
+	result := CurrencyAnalytics{
+		Currency:    currency,
+		Count:       len(series),
+		Average:     mean(rates),
+		Highest:     series[0],
+		Lowest:      series[0],
+		LogReturns:  LogReturns(series),
+		MaxDrawdown: MaxDrawdown(series),
+		Percentiles: make(map[float64]float64, len(DefaultPercentiles)),
+	}
+	for _, exchange := range series {
+		if exchange.Rate > result.Highest.Rate {
+			result.Highest = exchange
+		}
+		if exchange.Rate < result.Lowest.Rate {
+			result.Lowest = exchange
+		}
+	}
+	result.RollingStdDev = RollingStdDev(result.LogReturns, window)
+	result.AnnualizedVolatility = AnnualizedVolatility(result.LogReturns)
+	for _, p := range DefaultPercentiles {
+		result.Percentiles[p] = percentileSorted(rates, p)
+	}
+	return result, nil
+}
+
+func runAnalyze(args []string) error {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	source := addDataFlags(fs)
+	currency := fs.String("currency", "USD", "Currency to analyze")
+	dates := addRangeFlags(fs)
+	window := fs.Int("window", 30, "Window size for the rolling standard deviation")
+	fs.Parse(args)
+
+	query, err := dates.query(*currency)
+	if err != nil {
+		return err
+	}
+	data, err := source.load()
+	if err != nil {
+		return err
+	}
+	analytics, err := AnalyzeCurrency(query.Filter(data), *currency, *window)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Currency: %s (%d observations)\n", analytics.Currency, analytics.Count)
+	fmt.Printf("Average Rate: %.4f\n", analytics.Average)
+	fmt.Printf("Highest Rate: %.4f (on %s)\n", analytics.Highest.Rate, analytics.Highest.Period)
+	fmt.Printf("Lowest Rate: %.4f (on %s)\n", analytics.Lowest.Rate, analytics.Lowest.Period)
+	fmt.Printf("Annualized Volatility: %.2f%%\n", analytics.AnnualizedVolatility*100)
+	if n := len(analytics.RollingStdDev); n > 0 {
+		last := analytics.RollingStdDev[n-1]
+		fmt.Printf("Rolling StdDev (%d): %.6f (on %s)\n", *window, last.Value, last.Period)
+	}
+	fmt.Printf("Max Drawdown: %.2f%% (%s %.4f -> %s %.4f)\n",
+		analytics.MaxDrawdown.Depth*100,
+		analytics.MaxDrawdown.Peak.Period, analytics.MaxDrawdown.Peak.Rate,
+		analytics.MaxDrawdown.Trough.Period, analytics.MaxDrawdown.Trough.Rate)
+	for _, p := range DefaultPercentiles {
+		fmt.Printf("P%-3g %.4f\n", p, analytics.Percentiles[p])
+	}
+	return nil
+}
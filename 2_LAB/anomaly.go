@@ -0,0 +1,288 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+type AnomalyMethod int
+
+const (
+	// ZScore scores a change by its distance from the mean in standard deviations.
+	ZScore AnomalyMethod = iota
+	// MAD scores a change with the modified z-score, which uses the median and the median
+	// absolute deviation and is not skewed by the outliers it is looking for.
+	MAD
+)
+
+func (m AnomalyMethod) String() string {
+	switch m {
+	case ZScore:
+		return "zscore"
+	case MAD:
+		return "mad"
+	}
+	return fmt.Sprintf("AnomalyMethod(%d)", int(m))
+}
+
+func ParseAnomalyMethod(s string) (AnomalyMethod, error) {
+	for _, method := range []AnomalyMethod{ZScore, MAD} {
+		if method.String() == s {
+			return method, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown anomaly method %q (expected zscore or mad)", s)
+}
+
+// DefaultThreshold is the score above which a change is reported when no threshold is
+// given. Daily rate changes have fat tails, so the defaults are well above the usual 3.
+func (m AnomalyMethod) DefaultThreshold() float64 {
+	if m == MAD {
+		return 10
+	}
+	return 6
+}
+
+type AnomalyOptions struct {
+	Method AnomalyMethod
+	// Threshold is the absolute score above which a change is an anomaly; zero uses the
+	// default of the method.
+	Threshold float64
+}
+
+func (o AnomalyOptions) threshold() float64 {
+	return cmp.Or(o.Threshold, o.Method.DefaultThreshold())
+}
+
+type Anomaly struct {
+	Currency string  `json:"currency"`
+	Period   string  `json:"period"`
+	Rate     float64 `json:"rate"`
+	Previous float64 `json:"previous"`
+	Change   float64 `json:"change"`
+	Score    float64 `json:"score"`
+	// Reason is set for rows whose rate cannot be scored, such as a zero, negative or
+	// non-finite rate. Their Previous, Change and Score are zero, and so is a non-finite Rate.
+	Reason string `json:"reason,omitempty"`
+}
+
+// changeScorer turns a day-over-day log change into a score.
+type changeScorer func(change float64) float64
+
+func newChangeScorer(changes []float64, method AnomalyMethod) changeScorer {
+	if method == ZScore {
+		m, sd := mean(changes), stdDev(changes)
+		return func(change float64) float64 {
+			if sd == 0 {
+				return 0
+			}
+			return (change - m) / sd
+		}
+	}
+
+	sorted := append([]float64(nil), changes...)
+	sort.Float64s(sorted)
+	median := percentileSorted(sorted, 50)
+	deviations := make([]float64, len(changes))
+	for i, change := range changes {
+		deviations[i] = math.Abs(change - median)
+	}
+	sort.Float64s(deviations)
+	// 0.6745 makes the MAD comparable to a standard deviation for normal data. When more
+	// than half of the changes are equal, as with weekend rows repeating Friday's rate,
+	// the MAD is zero and the mean absolute deviation is used instead.
+	scale := percentileSorted(deviations, 50) / 0.6745
+	if scale == 0 {
+		scale = mean(deviations) * 1.2533
+	}
+	return func(change float64) float64 {
+		if scale == 0 {
+			return 0
+		}
+		return (change - median) / scale
+	}
+}
+
+// DetectAnomalies flags rows of the currency whose log change from the previous accepted
+// rate scores above the threshold. A flagged row is not used as the previous rate of the
+// next one, so the return from a bad tick is not reported as well. A row that jumps away
+// from the last accepted rate but not from the flagged row before it is a new level, not
+// a bad tick, and is accepted. Rows without a usable rate have no log change; they are
+// always flagged and left out of the scoring.
+func DetectAnomalies(data []Exchange, currency string, opts AnomalyOptions) ([]Anomaly, error) {
+	all := currencySeries(data, currency)
+	if len(all) == 0 {
+		return nil, &UnknownCurrencyError{Currency: currency}
+	}
+	threshold := opts.threshold()
+
+	var anomalies []Anomaly
+	series := make([]Exchange, 0, len(all))
+	for _, exchange := range all {
+		if err := checkRate(exchange.Rate); err != nil {
+			anomaly := Anomaly{Currency: currency, Period: exchange.Period, Reason: err.Error()}
+			if !math.IsNaN(exchange.Rate) && !math.IsInf(exchange.Rate, 0) {
+				anomaly.Rate = exchange.Rate
+			}
+			anomalies = append(anomalies, anomaly)
+			continue
+		}
+		series = append(series, exchange)
+	}
+	if len(series) < 2 {
+		return anomalies, nil
+	}
+
+	changes := make([]float64, 0, len(series))
+	for i := 1; i < len(series); i++ {
+		changes = append(changes, math.Log(series[i].Rate/series[i-1].Rate))
+	}
+	score := newChangeScorer(changes, opts.Method)
+
+	unscored := len(anomalies)
+	accepted := 0
+	for i := 1; i < len(series); i++ {
+		change := math.Log(series[i].Rate / series[accepted].Rate)
+		s := score(change)
+		flagged := math.Abs(s) > threshold
+		if flagged && accepted != i-1 {
+			flagged = math.Abs(score(changes[i-1])) > threshold
+		}
+		if !flagged {
+			accepted = i
+			continue
+		}
+		anomalies = append(anomalies, Anomaly{
+			Currency: currency,
+			Period:   series[i].Period,
+			Rate:     series[i].Rate,
+			Previous: series[accepted].Rate,
+			Change:   change,
+			Score:    s,
+		})
+	}
+	if unscored > 0 {
+		slices.SortStableFunc(anomalies, func(a, b Anomaly) int {
+			return cmp.Compare(a.Period, b.Period)
+		})
+	}
+	return anomalies, nil
+}
+
+// ExcludeAnomalies returns the rows of data that were not flagged.
+func ExcludeAnomalies(data []Exchange, anomalies []Anomaly) []Exchange {
+	if len(anomalies) == 0 {
+		return data
+	}
+	flagged := make(map[[2]string]bool, len(anomalies))
+	for _, anomaly := range anomalies {
+		flagged[[2]string{anomaly.Currency, anomaly.Period}] = true
+	}
+	result := make([]Exchange, 0, len(data))
+	for _, exchange := range data {
+		if !flagged[[2]string{exchange.Currency, exchange.Period}] {
+			result = append(result, exchange)
+		}
+	}
+	return result
+}
+
+// GetCurrencyStatsWithoutAnomalies is GetCurrencyStats over the rows of the currency
+// that DetectAnomalies does not flag.
+func GetCurrencyStatsWithoutAnomalies(data []Exchange, currency string, opts AnomalyOptions) (float64, Exchange, Exchange, error) {
+	anomalies, err := DetectAnomalies(data, currency, opts)
+	if err != nil {
+		return 0, Exchange{}, Exchange{}, err
+	}
+	return GetCurrencyStats(ExcludeAnomalies(data, anomalies), currency)
+}
+
+type anomalyFlags struct {
+	method    *string
+	threshold *float64
+}
+
+// addAnomalyFlags adds the detection flags, with their names prefixed for commands
+// where a bare -method or -threshold would be ambiguous.
+func addAnomalyFlags(fs *flag.FlagSet, prefix string) anomalyFlags {
+	return anomalyFlags{
+		method:    fs.String(prefix+"method", "mad", "Anomaly score: zscore or mad"),
+		threshold: fs.Float64(prefix+"threshold", 0, "Score above which a change is an anomaly (0 for the default of the method)"),
+	}
+}
+
+func (a anomalyFlags) options() (AnomalyOptions, error) {
+	method, err := ParseAnomalyMethod(*a.method)
+	if err != nil {
+		return AnomalyOptions{}, err
+	}
+	if *a.threshold < 0 {
+		return AnomalyOptions{}, fmt.Errorf("threshold must not be negative")
+	}
+	return AnomalyOptions{Method: method, Threshold: *a.threshold}, nil
+}
+
+func runAnomalies(args []string) error {
+	fs := flag.NewFlagSet("anomalies", flag.ExitOnError)
+	source := addDataFlags(fs)
+	currency := fs.String("currency", "", "Currency to check (all currencies when empty)")
+	dates := addRangeFlags(fs)
+	detection := addAnomalyFlags(fs, "")
+	format := addFormatFlag(fs)
+	fs.Parse(args)
+
+	query, err := dates.query(*currency)
+	if err != nil {
+		return err
+	}
+	opts, err := detection.options()
+	if err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	data, err := source.load()
+	if err != nil {
+		return err
+	}
+	data = query.Filter(data)
+
+	currencies := []string{*currency}
+	if *currency == "" {
+		currencies = ListCurrencies(data)
+	}
+	anomalies := []Anomaly{}
+	var rows [][]string
+	for _, c := range currencies {
+		found, err := DetectAnomalies(data, c, opts)
+		if err != nil {
+			return err
+		}
+		for _, anomaly := range found {
+			rows = append(rows, []string{
+				anomaly.Period,
+				anomaly.Currency,
+				strconv.FormatFloat(anomaly.Rate, 'f', -1, 64),
+				strconv.FormatFloat(anomaly.Previous, 'f', -1, 64),
+				strconv.FormatFloat(anomaly.Change*100, 'f', 2, 64) + "%",
+				strconv.FormatFloat(anomaly.Score, 'f', 1, 64),
+				anomaly.Reason,
+			})
+		}
+		anomalies = append(anomalies, found...)
+	}
+	if err := writeRows(os.Stdout, *format, []string{"Period", "Currency", "Rate", "Previous", "Change", "Score", "Reason"}, rows, anomalies); err != nil {
+		return err
+	}
+	if *format == "table" {
+		fmt.Printf("\n%d anomalies (%s, threshold %g)\n", len(anomalies), opts.Method, opts.threshold())
+	}
+	return nil
+}
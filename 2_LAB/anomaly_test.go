@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDetectAnomaliesUnusableRates(t *testing.T) {
+	rates := []float64{1.10, 1.11, 0, 1.10, math.NaN(), 1.11, -1, 1.10, math.Inf(1), 1.11, 1.10, 1.11}
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var data []Exchange
+	for i, rate := range rates {
+		date := start.AddDate(0, 0, i)
+		data = append(data, Exchange{Period: date.Format(PeriodLayout), Date: date, Currency: "USD", Rate: rate})
+	}
+
+	for _, method := range []AnomalyMethod{ZScore, MAD} {
+		anomalies, err := DetectAnomalies(data, "USD", AnomalyOptions{Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var periods []string
+		for _, anomaly := range anomalies {
+			periods = append(periods, anomaly.Period)
+			for _, f := range []float64{anomaly.Rate, anomaly.Previous, anomaly.Change, anomaly.Score} {
+				if math.IsNaN(f) || math.IsInf(f, 0) {
+					t.Errorf("%s: %+v has a non-finite field", method, anomaly)
+				}
+			}
+			if anomaly.Reason == "" {
+				t.Errorf("%s: %+v was flagged without a reason", method, anomaly)
+			}
+		}
+		want := []string{"2020-01-03", "2020-01-05", "2020-01-07", "2020-01-09"}
+		if len(periods) != len(want) {
+			t.Fatalf("%s: flagged %v, want %v", method, periods, want)
+		}
+		for i := range want {
+			if periods[i] != want[i] {
+				t.Errorf("%s: flagged %v, want %v", method, periods, want)
+				break
+			}
+		}
+		if _, err := json.Marshal(anomalies); err != nil {
+			t.Errorf("%s: %v", method, err)
+		}
+	}
+}
+
+func TestDetectAnomaliesSingleRow(t *testing.T) {
+	date := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	data := []Exchange{{Period: "2020-01-02", Date: date, Currency: "USD", Rate: 1.1}}
+	for _, method := range []AnomalyMethod{ZScore, MAD} {
+		anomalies, err := DetectAnomalies(data, "USD", AnomalyOptions{Method: method})
+		if err != nil || len(anomalies) != 0 {
+			t.Errorf("%s: got %v, %v", method, anomalies, err)
+		}
+	}
+}
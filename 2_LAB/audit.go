@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrAuditFailed = errors.New("audit failed")
+
+const (
+	CheckMalformed       = "malformed"
+	CheckDuplicate       = "duplicate"
+	CheckNonPositive     = "non-positive"
+	CheckNonFinite       = "non-finite"
+	CheckUnknownCurrency = "unknown-currency"
+	CheckOutOfOrder      = "out-of-order"
+	CheckGap             = "gap"
+)
+
+var AuditChecks = []string{CheckMalformed, CheckDuplicate, CheckNonPositive, CheckNonFinite, CheckUnknownCurrency, CheckOutOfOrder, CheckGap}
+
+// iso4217 holds the active ISO 4217 codes and the withdrawn ones that still appear in
+// historical euro reference rates.
+var iso4217 = func() map[string]bool {
+	codes := make(map[string]bool)
+	for _, code := range strings.Fields(`
+		AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB BOV
+		BRL BSD BTN BWP BYN BZD CAD CDF CHE CHF CHW CLF CLP CNY COP COU CRC CUC CUP CVE
+		CZK DJF DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF GTQ GYD HKD
+		HNL HTG HUF IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW KWD KYD
+		KZT LAK LBP LKR LRD LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MXV
+		MYR MZN NAD NGN NIO NOK NPR NZD OMR PAB PEN PGK PHP PKR PLN PYG QAR RON RSD RUB
+		RWF SAR SBD SCR SDG SEK SGD SHP SLE SLL SOS SRD SSP STN SVC SYP SZL THB TJS TMT
+		TND TOP TRY TTD TWD TZS UAH UGX USD USN UYI UYU UYW UZS VED VES VND VUV WST XAF
+		XAG XAU XBA XBB XBC XBD XCD XCG XDR XOF XPD XPF XPT XSU XTS XUA XXX YER ZAR ZMW
+		ZWG ZWL
+		CYP EEK HRK LTL LVL MTL ROL SIT SKK TRL`) {
+		codes[code] = true
+	}
+	return codes
+}()
+
+func IsISO4217(code string) bool {
+	return iso4217[code]
+}
+
+type AuditOptions struct {
+	Format string
+	// MaxGap is the longest run of business days without a rate that is not reported.
+	MaxGap int
+	// Checks selects the checks to run; all of them when empty.
+	Checks []string
+}
+
+// AuditIssue is a problem found in the input. Line is 0 for issues that do not belong
+// to a single row, such as gaps.
+type AuditIssue struct {
+	Line     int    `json:"line,omitempty"`
+	Check    string `json:"check"`
+	Period   string `json:"period"`
+	Currency string `json:"currency"`
+	Detail   string `json:"detail"`
+}
+
+type AuditReport struct {
+	Rows    int            `json:"rows"`
+	Skipped int            `json:"skipped"`
+	Counts  map[string]int `json:"counts"`
+	Issues  []AuditIssue   `json:"issues"`
+}
+
+func (r *AuditReport) add(issue AuditIssue) {
+	r.Counts[issue.Check]++
+	r.Issues = append(r.Issues, issue)
+}
+
+// Audit reads every row of the input and reports duplicate (period, currency) pairs,
+// non-positive rates, NaN and infinite rates, currencies that are not ISO 4217 codes, rows dated before an
+// earlier row of the same currency, and gaps longer than MaxGap business days. Rows
+// without a rate are counted as skipped, like the loader does, and not reported.
+func Audit(r io.Reader, opts AuditOptions) (*AuditReport, error) {
+	for _, check := range opts.Checks {
+		if !slices.Contains(AuditChecks, check) {
+			return nil, fmt.Errorf("unknown check %q (expected %s)", check, strings.Join(AuditChecks, ", "))
+		}
+	}
+	enabled := func(check string) bool {
+		return len(opts.Checks) == 0 || slices.Contains(opts.Checks, check)
+	}
+
+	source, err := NewRowSource(r, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+	report := &AuditReport{Counts: make(map[string]int)}
+	// seen keeps the first row of every (period, currency) pair with its line.
+	type seenRow struct {
+		line int
+		rate float64
+	}
+	seen := make(map[[2]string]seenRow)
+	latest := make(map[string]Exchange)
+	var data []Exchange
+	for {
+		row, err := source.Next()
+		if err == io.EOF {
+			break
+		}
+		report.Rows++
+		var rowErr *MalformedRowError
+		if err != nil && !errors.As(err, &rowErr) {
+			return nil, err
+		}
+		var exchange Exchange
+		if rowErr == nil {
+			exchange, rowErr = parseRawRow(row)
+		}
+		if rowErr != nil {
+			if rowErr.Reason == ReasonMissingRate {
+				report.Skipped++
+				continue
+			}
+			check, detail := CheckMalformed, rowErr.Reason
+			switch {
+			case errors.Is(rowErr, ErrNonPositiveRate):
+				check, detail = CheckNonPositive, "rate "+row.Rate
+			case errors.Is(rowErr, ErrNonFiniteRate):
+				check, detail = CheckNonFinite, "rate "+row.Rate
+			case rowErr.Err != nil:
+				detail += ": " + rowErr.Err.Error()
+			}
+			if enabled(check) {
+				report.add(AuditIssue{Line: rowErr.Line, Check: check, Period: row.Period, Currency: row.Currency, Detail: detail})
+			}
+			continue
+		}
+		issue := AuditIssue{Line: row.Line, Period: exchange.Period, Currency: exchange.Currency}
+
+		key := [2]string{exchange.Period, exchange.Currency}
+		if first, ok := seen[key]; ok {
+			if enabled(CheckDuplicate) {
+				issue.Check, issue.Detail = CheckDuplicate, fmt.Sprintf("same period and currency as line %d", first.line)
+				if first.rate != exchange.Rate {
+					issue.Detail += fmt.Sprintf(" with a different rate (%v, was %v)", exchange.Rate, first.rate)
+				}
+				report.add(issue)
+			}
+			continue
+		}
+		seen[key] = seenRow{line: row.Line, rate: exchange.Rate}
+		data = append(data, exchange)
+
+		if !IsISO4217(exchange.Currency) && enabled(CheckUnknownCurrency) {
+			issue.Check, issue.Detail = CheckUnknownCurrency, fmt.Sprintf("%q is not an ISO 4217 code", exchange.Currency)
+			report.add(issue)
+		}
+		if last, ok := latest[exchange.Currency]; ok && exchange.Date.Before(last.Date) {
+			if enabled(CheckOutOfOrder) {
+				issue.Check, issue.Detail = CheckOutOfOrder, fmt.Sprintf("after %s on line %d", last.Period, seen[[2]string{last.Period, last.Currency}].line)
+				report.add(issue)
+			}
+		} else {
+			latest[exchange.Currency] = exchange
+		}
+	}
+
+	if enabled(CheckGap) {
+		for _, currency := range ListCurrencies(data) {
+			for _, gap := range FindGaps(data, currency) {
+				if gap.Days <= opts.MaxGap {
+					continue
+				}
+				report.add(AuditIssue{
+					Check:    CheckGap,
+					Period:   gap.Start.Format(PeriodLayout),
+					Currency: currency,
+					Detail:   fmt.Sprintf("%d business days without a rate until %s", gap.Days, gap.End.Format(PeriodLayout)),
+				})
+			}
+		}
+	}
+	return report, nil
+}
+
+func runAudit(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	file := fs.String("file", defaultDataFile(), "Path to the exchange rates file or rate log")
+	input := fs.String("input-format", FormatAuto, "Input format: store, csv, json, xml or auto")
+	checks := fs.String("checks", "", "Comma-separated checks to run (all when empty): "+strings.Join(AuditChecks, ", "))
+	maxGap := fs.Int("max-gap", 3, "Longest run of business days without a rate that is not reported")
+	show := fs.Int("show", 10, "Show at most N issues of every check in the table (0 for all)")
+	format := addFormatFlag(fs)
+	fs.Parse(args)
+
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	opts := AuditOptions{Format: *input, MaxGap: *maxGap}
+	if *checks != "" {
+		opts.Checks = strings.Split(*checks, ",")
+	}
+	f, err := openDataFile(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	report, err := Audit(f, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *file, err)
+	}
+
+	shown := make(map[string]int)
+	var rows [][]string
+	for _, issue := range report.Issues {
+		if *format == "table" && *show > 0 && shown[issue.Check] >= *show {
+			continue
+		}
+		shown[issue.Check]++
+		line := "-"
+		if issue.Line > 0 {
+			line = strconv.Itoa(issue.Line)
+		}
+		rows = append(rows, []string{line, issue.Check, issue.Period, issue.Currency, issue.Detail})
+	}
+	if len(rows) > 0 || *format != "table" {
+		if err := writeRows(os.Stdout, *format, []string{"Line", "Check", "Period", "Currency", "Detail"}, rows, report); err != nil {
+			return err
+		}
+	}
+	if *format == "table" {
+		fmt.Printf("\nAudited %d rows (%d without a rate skipped)\n", report.Rows, report.Skipped)
+		for _, check := range AuditChecks {
+			if count := report.Counts[check]; count > 0 {
+				fmt.Printf("  %-17s %d\n", check+":", count)
+			}
+		}
+	}
+	if len(report.Issues) > 0 {
+		return fmt.Errorf("%w: %d issues in %s", ErrAuditFailed, len(report.Issues), *file)
+	}
+	return nil
+}
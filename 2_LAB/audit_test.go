@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditUnusableRates(t *testing.T) {
+	csv := "Period;Currency;Rate\n" +
+		"2020-01-02;USD;1.1193\n" +
+		"2020-01-02;JPY;0\n" +
+		"2020-01-02;GBP;-0.85\n" +
+		"2020-01-02;CHF;NaN\n" +
+		"2020-01-02;SEK;+Inf\n" +
+		"2020-01-02;NOK;abc\n"
+	report, err := Audit(strings.NewReader(csv), AuditOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{CheckNonPositive: 2, CheckNonFinite: 2, CheckMalformed: 1}
+	for check, count := range want {
+		if report.Counts[check] != count {
+			t.Errorf("%s: got %d issues, want %d (%+v)", check, report.Counts[check], count, report.Issues)
+		}
+	}
+	for _, issue := range report.Issues {
+		if issue.Check == CheckNonFinite && issue.Currency != "CHF" && issue.Currency != "SEK" {
+			t.Errorf("unexpected non-finite issue %+v", issue)
+		}
+	}
+
+	report, err = Audit(strings.NewReader(csv), AuditOptions{Checks: []string{CheckNonFinite}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Issues) != 2 || report.Counts[CheckNonPositive] != 0 {
+		t.Errorf("only non-finite: got %+v", report.Issues)
+	}
+}
+
+func TestAuditRateLogUnusableRates(t *testing.T) {
+	date := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	input := []byte(rateLogMagic)
+	for currency, rate := range map[string]float64{"USD": 1.1193, "JPY": 0, "CHF": math.NaN()} {
+		key, err := encodeRateLogKey(Exchange{Date: date, Currency: currency})
+		if err != nil {
+			t.Fatal(err)
+		}
+		input = appendRateLogRecord(input, key, rate)
+	}
+	report, err := Audit(bytes.NewReader(input), AuditOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Counts[CheckNonPositive] != 1 || report.Counts[CheckNonFinite] != 1 || report.Counts[CheckMalformed] != 0 {
+		t.Errorf("got %v, want one non-positive and one non-finite rate", report.Counts)
+	}
+	for _, issue := range report.Issues {
+		if issue.Period != "2020-01-02" || issue.Currency == "" {
+			t.Errorf("issue %+v does not name its row", issue)
+		}
+	}
+}
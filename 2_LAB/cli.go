@@ -0,0 +1,518 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+)
+
+const DefaultDataFile = "./euro-exchange-rates.csv"
+
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+var commands = []command{
+	{"stats", "Average, highest and lowest rate of a currency", runStats},
+	{"sort", "Sort exchange rates by rate, currency or period", runSort},
+	{"convert", "Convert an amount between two currencies", runConvert},
+	{"list-currencies", "List currencies available in the data", runListCurrencies},
+	{"range", "Show rates of a currency within a date range", runRange},
+	{"cross", "Derive cross rates for a non-EUR currency pair", runCross},
+	{"resample", "Aggregate a currency into OHLC bars", runResample},
+	{"analyze", "Volatility, return and drawdown analytics", runAnalyze},
+	{"correlation", "Correlation matrix of daily returns", runCorrelation},
+	{"gaps", "Report missing business days and fill them", runGaps},
+	{"anomalies", "Detect outlying day-over-day rate changes", runAnomalies},
+	{"forecast", "Forecast a currency and backtest the models", runForecast},
+	{"plot", "Chart rate history as a sparkline and PNG", runPlot},
+	{"portfolio", "Value currency holdings over time with profit and loss", runPortfolio},
+	{"audit", "Validate the data and exit non-zero on problems", runAudit},
+	{"import", "Append exchange rates to the binary rate log", runImport},
+	{"serve", "Serve rates, conversions and stats over HTTP", runServe},
+}
+
+func runCLI(args []string) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(os.Stdout)
+		return nil
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
+		}
+	}
+	printUsage(os.Stderr)
+	return fmt.Errorf("unknown command: %s", args[0])
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  go run . <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-16s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'go run . <command> -h' to see the flags of a command.")
+}
+
+type dataFlags struct {
+	file   *string
+	input  *string
+	strict *bool
+	report *bool
+}
+
+type rangeFlags struct {
+	from *string
+	to   *string
+}
+
+func addDataFlags(fs *flag.FlagSet) dataFlags {
+	return addDataFlagsWithDefault(fs, defaultDataFile())
+}
+
+func addDataFlagsWithDefault(fs *flag.FlagSet, file string) dataFlags {
+	return dataFlags{
+		file:   fs.String("file", file, "Path to the exchange rates file or rate log"),
+		input:  fs.String("input-format", FormatAuto, "Input format: store, csv, json, xml or auto"),
+		strict: fs.Bool("strict", false, "Fail on the first malformed row instead of skipping it"),
+		report: fs.Bool("report", false, "Print a summary of skipped rows to stderr"),
+	}
+}
+
+func (d dataFlags) options() LoadOptions {
+	opts := LoadOptions{Policy: SkipInvalidRows, Format: *d.input}
+	if *d.strict {
+		opts.Policy = FailOnInvalidRow
+	}
+	return opts
+}
+
+func (d dataFlags) load() ([]Exchange, error) {
+	data, report, err := LoadExchangeRatesWithOptions(*d.file, d.options())
+	if err != nil {
+		return nil, err
+	}
+	d.done(report)
+	return data, nil
+}
+
+// stream passes every row matched by the query to fn without loading the whole file.
+func (d dataFlags) stream(query ExchangeQuery, fn func(Exchange)) error {
+	reader, err := OpenExchangeReader(*d.file, d.options())
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	for exchange, err := range FilterSeq(reader.All(), query) {
+		if err != nil {
+			return err
+		}
+		fn(exchange)
+	}
+	d.done(reader.Report())
+	return nil
+}
+
+func (d dataFlags) done(report *LoadReport) {
+	if *d.report {
+		printLoadReport(os.Stderr, report)
+	}
+}
+
+func printLoadReport(w io.Writer, report *LoadReport) {
+	fmt.Fprintf(w, "Loaded %d rows, skipped %d\n", report.Loaded, report.Skipped)
+	reasons := make([]string, 0, len(report.Reasons))
+	for reason := range report.Reasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Fprintf(w, "  %-16s %d\n", reason+":", report.Reasons[reason])
+	}
+}
+
+func addRangeFlags(fs *flag.FlagSet) rangeFlags {
+	return rangeFlags{
+		from: fs.String("from", "", "Start period in YYYY-MM-DD format"),
+		to:   fs.String("to", "", "End period in YYYY-MM-DD format"),
+	}
+}
+
+func addFormatFlag(fs *flag.FlagSet) *string {
+	return fs.String("format", "table", "Output format: table, csv or json")
+}
+
+func (r rangeFlags) query(currency string) (ExchangeQuery, error) {
+	query := ExchangeQuery{Currency: currency}
+	var err error
+	if *r.from != "" {
+		if query.From, err = ParsePeriod(*r.from); err != nil {
+			return query, err
+		}
+	}
+	if *r.to != "" {
+		if query.To, err = ParsePeriod(*r.to); err != nil {
+			return query, err
+		}
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && query.To.Before(query.From) {
+		return query, fmt.Errorf("end period %s is before start period %s", *r.to, *r.from)
+	}
+	return query, nil
+}
+
+func checkFormat(format string) error {
+	switch format {
+	case "table", "csv", "json":
+		return nil
+	}
+	return fmt.Errorf("unknown format %q (expected table, csv or json)", format)
+}
+
+// writeRows renders a header and rows in the requested format. JSON output is taken
+// from value so that it keeps proper types instead of formatted strings.
+func writeRows(w io.Writer, format string, header []string, rows [][]string, value any) error {
+	switch format {
+	case "csv":
+		writer := csv.NewWriter(w)
+		writer.Comma = ';'
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+		if err := writer.WriteAll(rows); err != nil {
+			return err
+		}
+		return writer.Error()
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for i, cell := range header {
+			if i > 0 {
+				fmt.Fprint(tw, "\t")
+			}
+			fmt.Fprint(tw, cell)
+		}
+		fmt.Fprintln(tw)
+		for _, row := range rows {
+			for i, cell := range row {
+				if i > 0 {
+					fmt.Fprint(tw, "\t")
+				}
+				fmt.Fprint(tw, cell)
+			}
+			fmt.Fprintln(tw)
+		}
+		return tw.Flush()
+	}
+}
+
+func writeExchanges(w io.Writer, format string, data []Exchange) error {
+	rows := make([][]string, len(data))
+	for i, exchange := range data {
+		rows[i] = []string{exchange.Period, exchange.Currency, exchange.Exact.String()}
+	}
+	if data == nil {
+		data = []Exchange{}
+	}
+	return writeRows(w, format, []string{"Period", "Currency", "Rate"}, rows, data)
+}
+
+type statsResult struct {
+	Currency string   `json:"currency"`
+	Average  Decimal  `json:"average"`
+	Highest  Exchange `json:"highest"`
+	Lowest   Exchange `json:"lowest"`
+}
+
+func newStatsResult(stats *StatsAccumulator, mode RoundingMode) (statsResult, error) {
+	_, highest, lowest, err := stats.Result()
+	if err != nil {
+		return statsResult{}, err
+	}
+	average, err := stats.ExactAverage(RateScale, mode)
+	if err != nil {
+		return statsResult{}, err
+	}
+	return statsResult{Currency: stats.Currency, Average: average, Highest: highest, Lowest: lowest}, nil
+}
+
+func addRoundingFlag(fs *flag.FlagSet) *string {
+	return fs.String("rounding", RoundHalfEven.String(), "Rounding mode: half-even or half-up")
+}
+
+func runStats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	source := addDataFlags(fs)
+	currency := fs.String("currency", "USD", "Currency to analyze")
+	dates := addRangeFlags(fs)
+	rounding := addRoundingFlag(fs)
+	excludeAnomalies := fs.Bool("exclude-anomalies", false, "Leave out rows flagged by the anomaly detector")
+	detection := addAnomalyFlags(fs, "anomaly-")
+	format := addFormatFlag(fs)
+	fs.Parse(args)
+
+	query, err := dates.query(*currency)
+	if err != nil {
+		return err
+	}
+	mode, err := ParseRoundingMode(*rounding)
+	if err != nil {
+		return err
+	}
+	opts, err := detection.options()
+	if err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+
+	stats := NewStatsAccumulator(*currency)
+	var anomalies []Anomaly
+	if *excludeAnomalies {
+		data, err := source.load()
+		if err != nil {
+			return err
+		}
+		data = query.Filter(data)
+		if anomalies, err = DetectAnomalies(data, *currency, opts); err != nil {
+			return err
+		}
+		for _, exchange := range ExcludeAnomalies(data, anomalies) {
+			stats.Add(exchange)
+		}
+	} else if err := source.stream(query, stats.Add); err != nil {
+		return err
+	}
+	result, err := newStatsResult(stats, mode)
+	if err != nil {
+		return err
+	}
+	if *format == "table" {
+		fmt.Printf("Average Rate: %s\n", result.Average.Round(4, mode))
+		fmt.Printf("Highest Rate: %.4f (on %s)\n", result.Highest.Rate, result.Highest.Period)
+		fmt.Printf("Lowest Rate: %.4f (on %s)\n", result.Lowest.Rate, result.Lowest.Period)
+		if *excludeAnomalies {
+			fmt.Printf("Excluded Anomalies: %d\n", len(anomalies))
+		}
+		return nil
+	}
+	rows := [][]string{
+		{"average", "", result.Average.String()},
+		{"highest", result.Highest.Period, result.Highest.Exact.String()},
+		{"lowest", result.Lowest.Period, result.Lowest.Exact.String()},
+	}
+	return writeRows(os.Stdout, *format, []string{"Statistic", "Period", "Rate"}, rows, result)
+}
+
+func runSort(args []string) error {
+	fs := flag.NewFlagSet("sort", flag.ExitOnError)
+	source := addDataFlags(fs)
+	currency := fs.String("currency", "", "Only include this currency (all currencies when empty)")
+	dates := addRangeFlags(fs)
+	key := fs.String("key", "rate", "Comma-separated sort keys (rate, currency, period), each optionally suffixed with :asc or :desc")
+	order := fs.String("order", "asc", "Default sort direction: asc or desc")
+	group := fs.String("group", "", "Comma-separated groups for -top and -bottom: currency, year, quarter or month")
+	top := fs.Int("top", 0, "Keep only the first N rows of every group")
+	bottom := fs.Int("bottom", 0, "Keep only the last N rows of every group")
+	businessDays := fs.Bool("business-days", false, "Skip weekend rows, which repeat Friday's rate")
+	limit := fs.Int("limit", 0, "Show only the first N rows (0 for all)")
+	format := addFormatFlag(fs)
+	fs.Parse(args)
+
+	query, err := dates.query(*currency)
+	if err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	var descending bool
+	switch *order {
+	case "asc":
+		descending = false
+	case "desc":
+		descending = true
+	default:
+		return fmt.Errorf("unknown sort direction %q (expected asc or desc)", *order)
+	}
+	keys, err := ParseSortKeys(*key, descending)
+	if err != nil {
+		return err
+	}
+	groups, err := ParseGroupFields(*group)
+	if err != nil {
+		return err
+	}
+
+	data, err := source.load()
+	if err != nil {
+		return err
+	}
+	ranking := Ranking{Keys: keys, Group: groups, Top: *top, Bottom: *bottom, BusinessDays: *businessDays}
+	data = ranking.Apply(query.Filter(data))
+	if *limit > 0 && len(data) > *limit {
+		data = data[:*limit]
+	}
+	return writeExchanges(os.Stdout, *format, data)
+}
+
+type conversionResult struct {
+	Period   string  `json:"period"`
+	Amount   Decimal `json:"amount"`
+	From     string  `json:"from"`
+	To       string  `json:"to"`
+	Rate     Decimal `json:"rate"`
+	Result   Decimal `json:"result"`
+	Rounding string  `json:"rounding"`
+}
+
+func newConversionResult(amount, converted Money, rate CrossRate, mode RoundingMode) conversionResult {
+	return conversionResult{
+		Period:   rate.Period,
+		Amount:   amount.Amount,
+		From:     amount.Currency,
+		To:       converted.Currency,
+		Rate:     rate.Rate,
+		Result:   converted.Amount,
+		Rounding: mode.String(),
+	}
+}
+
+func runConvert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	source := addDataFlags(fs)
+	amount := fs.String("amount", "1", "Amount to convert")
+	from := fs.String("from", BaseCurrency, "Source currency")
+	to := fs.String("to", "USD", "Target currency")
+	period := fs.String("date", "", "Period in YYYY-MM-DD format, the latest rate on or before it is used (latest available when empty)")
+	rounding := addRoundingFlag(fs)
+	format := addFormatFlag(fs)
+	fs.Parse(args)
+
+	money, err := ParseMoney(*amount, *from)
+	if err != nil {
+		return err
+	}
+	mode, err := ParseRoundingMode(*rounding)
+	if err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+
+	data, err := source.load()
+	if err != nil {
+		return err
+	}
+	store := NewRateStore(data)
+	date := store.LastDate()
+	if *period != "" {
+		if date, err = ParsePeriod(*period); err != nil {
+			return err
+		}
+	}
+	converted, rate, err := store.Convert(money, *to, date, mode)
+	if err != nil {
+		return err
+	}
+
+	result := newConversionResult(money, converted, rate, mode)
+	if *format == "table" {
+		fmt.Printf("%s = %s (rate %s on %s, %s rounding)\n", money, converted, result.Rate, result.Period, result.Rounding)
+		return nil
+	}
+	rows := [][]string{{
+		result.Period,
+		result.Amount.String(),
+		result.From,
+		result.To,
+		result.Rate.String(),
+		result.Result.String(),
+		result.Rounding,
+	}}
+	return writeRows(os.Stdout, *format, []string{"Period", "Amount", "From", "To", "Rate", "Result", "Rounding"}, rows, result)
+}
+
+type currencySummary struct {
+	Currency string `json:"currency"`
+	Count    int    `json:"count"`
+	First    string `json:"first"`
+	Last     string `json:"last"`
+}
+
+func runListCurrencies(args []string) error {
+	fs := flag.NewFlagSet("list-currencies", flag.ExitOnError)
+	source := addDataFlags(fs)
+	format := addFormatFlag(fs)
+	fs.Parse(args)
+
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+
+	data, err := source.load()
+	if err != nil {
+		return err
+	}
+	summaries := make(map[string]*currencySummary)
+	for _, exchange := range data {
+		summary, ok := summaries[exchange.Currency]
+		if !ok {
+			summary = &currencySummary{Currency: exchange.Currency, First: exchange.Period, Last: exchange.Period}
+			summaries[exchange.Currency] = summary
+		}
+		summary.Count++
+		summary.First = min(summary.First, exchange.Period)
+		summary.Last = max(summary.Last, exchange.Period)
+	}
+
+	result := []currencySummary{}
+	var rows [][]string
+	for _, currency := range ListCurrencies(data) {
+		summary := summaries[currency]
+		result = append(result, *summary)
+		rows = append(rows, []string{summary.Currency, strconv.Itoa(summary.Count), summary.First, summary.Last})
+	}
+	return writeRows(os.Stdout, *format, []string{"Currency", "Count", "First", "Last"}, rows, result)
+}
+
+func runRange(args []string) error {
+	fs := flag.NewFlagSet("range", flag.ExitOnError)
+	source := addDataFlags(fs)
+	currency := fs.String("currency", "USD", "Currency to show")
+	dates := addRangeFlags(fs)
+	format := addFormatFlag(fs)
+	fs.Parse(args)
+
+	query, err := dates.query(*currency)
+	if err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+
+	data, err := source.load()
+	if err != nil {
+		return err
+	}
+	data = SortExchangeRatesByPeriod(query.Filter(data), true)
+	return writeExchanges(os.Stdout, *format, data)
+}
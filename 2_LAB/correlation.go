@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+)
+
+type CorrelationMatrix struct {
+	Currencies   []string
+	Pearson      [][]float64
+	Spearman     [][]float64
+	Observations int
+}
+
+// AlignByPeriod returns the periods (in order) where every given currency has a rate,
+// together with the rate series of each currency for those periods.
+func AlignByPeriod(data []Exchange, currencies []string) ([]string, map[string][]float64) {
+	byPeriod := make(map[string]map[string]float64)
+	for _, exchange := range data {
+		if byPeriod[exchange.Period] == nil {
+			byPeriod[exchange.Period] = make(map[string]float64)
+		}
+		byPeriod[exchange.Period][exchange.Currency] = exchange.Rate
+	}
+
+	var periods []string
+	for period, rates := range byPeriod {
+		complete := true
+		for _, currency := range currencies {
+			if _, ok := rates[currency]; !ok {
+				complete = false
+				break
+			}
+		}
+		if complete {
+			periods = append(periods, period)
+		}
+	}
+	sort.Strings(periods)
+
+	series := make(map[string][]float64, len(currencies))
+	for _, currency := range currencies {
+		values := make([]float64, len(periods))
+		for i, period := range periods {
+			values[i] = byPeriod[period][currency]
+		}
+		series[currency] = values
+	}
+	return periods, series
+}
+
+func ListCurrencies(data []Exchange) []string {
+	seen := make(map[string]bool)
+	var currencies []string
+	for _, exchange := range data {
+		if !seen[exchange.Currency] {
+			seen[exchange.Currency] = true
+			currencies = append(currencies, exchange.Currency)
+		}
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+func logReturnValues(rates []float64) []float64 {
+	if len(rates) < 2 {
+		return nil
+	}
+	returns := make([]float64, len(rates)-1)
+	for i := 1; i < len(rates); i++ {
+		returns[i-1] = math.Log(rates[i] / rates[i-1])
+	}
+	return returns
+}
+
+func Pearson(x, y []float64) float64 {
+	if len(x) != len(y) || len(x) < 2 {
+		return math.NaN()
+	}
+	mx, my := mean(x), mean(y)
+	var cov, vx, vy float64
+	for i := range x {
+		dx, dy := x[i]-mx, y[i]-my
+		cov += dx * dy
+		vx += dx * dx
+		vy += dy * dy
+	}
+	if vx == 0 || vy == 0 {
+		return math.NaN()
+	}
+	return cov / math.Sqrt(vx*vy)
+}
+
+func Spearman(x, y []float64) float64 {
+	return Pearson(ranks(x), ranks(y))
+}
+
+// ranks assigns 1-based ranks, giving tied values the average of their ranks.
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return values[order[i]] < values[order[j]]
+	})
+
+	result := make([]float64, len(values))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && values[order[j+1]] == values[order[i]] {
+			j++
+		}
+		rank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			result[order[k]] = rank
+		}
+		i = j + 1
+	}
+	return result
+}
+
+// Correlate computes the correlation of daily log returns for every currency pair over
+// the rows matched by the query. When last is positive only the most recent last aligned
+// periods are used.
+func Correlate(data []Exchange, query ExchangeQuery, last int) (CorrelationMatrix, error) {
+	query.Currency = ""
+	data = query.Filter(data)
+	currencies := ListCurrencies(data)
+	periods, series := AlignByPeriod(data, currencies)
+	if last > 0 && len(periods) > last {
+		for currency, values := range series {
+			series[currency] = values[len(values)-last:]
+		}
+		periods = periods[len(periods)-last:]
+	}
+	if len(periods) < 3 {
+		return CorrelationMatrix{}, fmt.Errorf("not enough aligned periods to correlate (%d)", len(periods))
+	}
+
+	returns := make(map[string][]float64, len(currencies))
+	for currency, values := range series {
+		returns[currency] = logReturnValues(values)
+	}
+
+	matrix := CorrelationMatrix{
+		Currencies:   currencies,
+		Pearson:      make([][]float64, len(currencies)),
+		Spearman:     make([][]float64, len(currencies)),
+		Observations: len(periods) - 1,
+	}
+	for i, a := range currencies {
+		matrix.Pearson[i] = make([]float64, len(currencies))
+		matrix.Spearman[i] = make([]float64, len(currencies))
+		for j, b := range currencies {
+			matrix.Pearson[i][j] = Pearson(returns[a], returns[b])
+			matrix.Spearman[i][j] = Spearman(returns[a], returns[b])
+		}
+	}
+	return matrix, nil
+}
+
+func (m CorrelationMatrix) values(method string) ([][]float64, error) {
+	switch method {
+	case "pearson":
+		return m.Pearson, nil
+	case "spearman":
+		return m.Spearman, nil
+	}
+	return nil, fmt.Errorf("unknown correlation method %q (expected pearson or spearman)", method)
+}
+
+func PrintCorrelationTable(w io.Writer, m CorrelationMatrix, method string) error {
+	values, err := m.values(method)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Correlation of daily log returns (%s, %d observations)\n", method, m.Observations)
+	fmt.Fprintf(w, "%-6s", "")
+	for _, currency := range m.Currencies {
+		fmt.Fprintf(w, "%8s", currency)
+	}
+	fmt.Fprintln(w)
+	for i, currency := range m.Currencies {
+		fmt.Fprintf(w, "%-6s", currency)
+		for j := range m.Currencies {
+			fmt.Fprintf(w, "%8.3f", values[i][j])
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+func WriteCorrelationCSV(w io.Writer, m CorrelationMatrix, method string) error {
+	values, err := m.values(method)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	writer.Comma = ';'
+	if err := writer.Write(append([]string{"Currency"}, m.Currencies...)); err != nil {
+		return err
+	}
+	for i, currency := range m.Currencies {
+		row := []string{currency}
+		for j := range m.Currencies {
+			row = append(row, strconv.FormatFloat(values[i][j], 'f', 6, 64))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func runCorrelation(args []string) error {
+	fs := flag.NewFlagSet("correlation", flag.ExitOnError)
+	source := addDataFlags(fs)
+	dates := addRangeFlags(fs)
+	last := fs.Int("last", 0, "Use only the last N aligned periods (0 for all)")
+	method := fs.String("method", "pearson", "Correlation method: pearson or spearman")
+	output := fs.String("csv", "", "Export the matrix to this CSV file")
+	fs.Parse(args)
+
+	query, err := dates.query("")
+	if err != nil {
+		return err
+	}
+
+	data, err := source.load()
+	if err != nil {
+		return err
+	}
+	matrix, err := Correlate(data, query, *last)
+	if err != nil {
+		return err
+	}
+	if err := PrintCorrelationTable(os.Stdout, matrix, *method); err != nil {
+		return err
+	}
+	if *output == "" {
+		return nil
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := WriteCorrelationCSV(f, matrix, *method); err != nil {
+		return err
+	}
+	fmt.Printf("\nCorrelation matrix saved to: %s\n", *output)
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+)
+
+const BaseCurrency = "EUR"
+
+// CrossRate is a base/quote rate, rounded half-even to RateScale places unless it was
+// derived for a conversion with another rounding mode.
+type CrossRate struct {
+	Period string
+	Base   string
+	Quote  string
+	Rate   Decimal
+}
+
+type MissingLegError struct {
+	Period   string
+	Currency string
+}
+
+func (e *MissingLegError) Error() string {
+	return fmt.Sprintf("no %s/%s rate for %s", BaseCurrency, e.Currency, e.Period)
+}
+
+type CrossRateTable struct {
+	legs       map[string]map[string]Decimal
+	periods    []string
+	currencies map[string]bool
+}
+
+func NewCrossRateTable(data []Exchange) *CrossRateTable {
+	table := &CrossRateTable{
+		legs:       make(map[string]map[string]Decimal),
+		currencies: map[string]bool{BaseCurrency: true},
+	}
+	for _, exchange := range data {
+		legs, ok := table.legs[exchange.Period]
+		if !ok {
+			legs = make(map[string]Decimal)
+			table.legs[exchange.Period] = legs
+			table.periods = append(table.periods, exchange.Period)
+		}
+		legs[exchange.Currency] = exchange.Exact
+		table.currencies[exchange.Currency] = true
+	}
+	sort.Strings(table.periods)
+	return table
+}
+
+func (t *CrossRateTable) leg(period, currency string) (Decimal, error) {
+	if currency == BaseCurrency {
+		return NewDecimal(1, 0), nil
+	}
+	rate, ok := t.legs[period][currency]
+	if !ok || rate.Sign() <= 0 {
+		return Decimal{}, &MissingLegError{Period: period, Currency: currency}
+	}
+	return rate, nil
+}
+
+// Rate returns the base/quote rate, i.e. how many units of quote one unit of base buys.
+func (t *CrossRateTable) Rate(base, quote, period string) (CrossRate, error) {
+	for _, currency := range []string{base, quote} {
+		if !t.currencies[currency] {
+			return CrossRate{}, &UnknownCurrencyError{Currency: currency}
+		}
+	}
+	baseLeg, err := t.leg(period, base)
+	if err != nil {
+		return CrossRate{}, err
+	}
+	quoteLeg, err := t.leg(period, quote)
+	if err != nil {
+		return CrossRate{}, err
+	}
+	rate, err := quoteLeg.Quo(baseLeg, RateScale, RoundHalfEven)
+	if err != nil {
+		return CrossRate{}, err
+	}
+	return CrossRate{Period: period, Base: base, Quote: quote, Rate: rate}, nil
+}
+
+// Series returns base/quote rates for every period along with the periods where a leg was missing.
+func (t *CrossRateTable) Series(base, quote string) ([]CrossRate, []*MissingLegError, error) {
+	var rates []CrossRate
+	var missing []*MissingLegError
+	for _, period := range t.periods {
+		rate, err := t.Rate(base, quote, period)
+		if err != nil {
+			if legErr, ok := err.(*MissingLegError); ok {
+				missing = append(missing, legErr)
+				continue
+			}
+			return nil, nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, missing, nil
+}
+
+func GetCrossRate(data []Exchange, base, quote, period string) (CrossRate, error) {
+	return NewCrossRateTable(data).Rate(base, quote, period)
+}
+
+func runCross(args []string) error {
+	fs := flag.NewFlagSet("cross", flag.ExitOnError)
+	source := addDataFlags(fs)
+	base := fs.String("base", "USD", "Base currency")
+	quote := fs.String("quote", "GBP", "Quote currency")
+	period := fs.String("period", "", "Period in YYYY-MM-DD format, using the latest rates on or before it (all periods when empty)")
+	fs.Parse(args)
+
+	data, err := source.load()
+	if err != nil {
+		return err
+	}
+
+	if *period != "" {
+		date, err := ParsePeriod(*period)
+		if err != nil {
+			return err
+		}
+		// Like convert -date, a day without rates (a holiday) uses the latest earlier legs.
+		rate, err := NewRateStore(data).CrossRate(*base, *quote, date)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s %s/%s: %s\n", rate.Period, rate.Base, rate.Quote, rate.Rate)
+		return nil
+	}
+
+	rates, missing, err := NewCrossRateTable(data).Series(*base, *quote)
+	if err != nil {
+		return err
+	}
+	for _, rate := range rates {
+		fmt.Printf("%s %s/%s: %s\n", rate.Period, rate.Base, rate.Quote, rate.Rate)
+	}
+	fmt.Printf("\n%d rates derived, %d periods skipped due to missing legs\n", len(rates), len(missing))
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	for input, want := range map[string]string{
+		"1.1193":  "1.1193",
+		" 120.5 ": "120.5",
+		"-0.005":  "-0.005",
+		"+7":      "7",
+		".5":      "0.5",
+		"3.":      "3",
+		"1.10":    "1.10",
+	} {
+		d, err := ParseDecimal(input)
+		if err != nil {
+			t.Errorf("%q: %v", input, err)
+			continue
+		}
+		if d.String() != want {
+			t.Errorf("%q: got %s, want %s", input, d, want)
+		}
+	}
+}
+
+func TestParseDecimalRejects(t *testing.T) {
+	for _, input := range []string{"", "-", ".", "1,5", "1.2.3", "1e3", "NaN", "Inf", "-Inf", "0x10", "--1", "1 000"} {
+		if d, err := ParseDecimal(input); err == nil {
+			t.Errorf("%q: got %s, want an error", input, d)
+		}
+	}
+}
+
+func TestDecimalFromFloat(t *testing.T) {
+	d, err := DecimalFromFloat(1.1193)
+	if err != nil || d.String() != "1.1193" {
+		t.Errorf("got %s, %v, want 1.1193", d, err)
+	}
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if d, err := DecimalFromFloat(f); err == nil {
+			t.Errorf("%v: got %s, want an error", f, d)
+		}
+	}
+}
+
+func TestDecimalRound(t *testing.T) {
+	tests := []struct {
+		input    string
+		scale    int32
+		halfEven string
+		halfUp   string
+	}{
+		{"0.125", 2, "0.12", "0.13"},
+		{"0.135", 2, "0.14", "0.14"},
+		{"-0.125", 2, "-0.12", "-0.13"},
+		{"-0.135", 2, "-0.14", "-0.14"},
+		{"2.5", 0, "2", "3"},
+		{"-2.5", 0, "-2", "-3"},
+		{"-0.5", 0, "0", "-1"},
+		{"1.126", 2, "1.13", "1.13"},
+		{"-1.124", 2, "-1.12", "-1.12"},
+		{"1.5", 3, "1.500", "1.500"},
+	}
+	for _, tt := range tests {
+		d, err := ParseDecimal(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for mode, want := range map[RoundingMode]string{RoundHalfEven: tt.halfEven, RoundHalfUp: tt.halfUp} {
+			if got := d.Round(tt.scale, mode).String(); got != want {
+				t.Errorf("%s rounded %s to %d places: got %s, want %s", tt.input, mode, tt.scale, got, want)
+			}
+		}
+	}
+}
+
+func TestDecimalQuo(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		scale    int32
+		halfEven string
+		halfUp   string
+	}{
+		{"1", "8", 2, "0.12", "0.13"},
+		{"-1", "8", 2, "-0.12", "-0.13"},
+		{"1", "-8", 2, "-0.12", "-0.13"},
+		{"-1", "-8", 2, "0.12", "0.13"},
+		{"3", "8", 2, "0.38", "0.38"},
+		{"1", "3", 4, "0.3333", "0.3333"},
+		{"-2", "3", 2, "-0.67", "-0.67"},
+		{"0.905", "1", 2, "0.90", "0.91"},
+		{"100", "1.1193", 2, "89.34", "89.34"},
+	}
+	for _, tt := range tests {
+		a, err := ParseDecimal(tt.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ParseDecimal(tt.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for mode, want := range map[RoundingMode]string{RoundHalfEven: tt.halfEven, RoundHalfUp: tt.halfUp} {
+			got, err := a.Quo(b, tt.scale, mode)
+			if err != nil {
+				t.Errorf("%s / %s: %v", tt.a, tt.b, err)
+				continue
+			}
+			if got.String() != want {
+				t.Errorf("%s / %s rounded %s to %d places: got %s, want %s", tt.a, tt.b, mode, tt.scale, got, want)
+			}
+		}
+	}
+	if _, err := NewDecimal(1, 0).Quo(Decimal{}, 2, RoundHalfEven); err != ErrDivisionByZero {
+		t.Errorf("division by zero: got %v", err)
+	}
+}
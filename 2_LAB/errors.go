@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrFileNotFound    = errors.New("file not found")
+	ErrMalformedRow    = errors.New("malformed row")
+	ErrUnknownCurrency = errors.New("unknown currency")
+	// ErrNonFiniteRate and ErrNonPositiveRate are the Err of the MalformedRowError of rows
+	// whose rate parses as a number that no ratio or logarithm can use.
+	ErrNonFiniteRate   = errors.New("rate is not a finite number")
+	ErrNonPositiveRate = errors.New("rate is not positive")
+)
+
+type FileNotFoundError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileNotFoundError) Error() string {
+	return fmt.Sprintf("could not read file %s: %v", e.Path, e.Err)
+}
+
+func (e *FileNotFoundError) Is(target error) bool {
+	return target == ErrFileNotFound
+}
+
+func (e *FileNotFoundError) Unwrap() error {
+	return e.Err
+}
+
+type MalformedRowError struct {
+	Line   int
+	Reason string
+	Err    error
+}
+
+func (e *MalformedRowError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("line %d: %s: %v", e.Line, e.Reason, e.Err)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+}
+
+func (e *MalformedRowError) Is(target error) bool {
+	return target == ErrMalformedRow
+}
+
+func (e *MalformedRowError) Unwrap() error {
+	return e.Err
+}
+
+type UnknownCurrencyError struct {
+	Currency string
+}
+
+func (e *UnknownCurrencyError) Error() string {
+	return fmt.Sprintf("currency %s not found in the data", e.Currency)
+}
+
+func (e *UnknownCurrencyError) Is(target error) bool {
+	return target == ErrUnknownCurrency
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ForecastModel projects a series of observations horizon steps ahead.
+type ForecastModel interface {
+	Name() string
+	Forecast(history []float64, horizon int) []float64
+}
+
+// MovingAverage forecasts the mean of the last Window observations for every step.
+type MovingAverage struct {
+	Window int
+}
+
+func (m MovingAverage) Name() string {
+	return "moving-average"
+}
+
+func (m MovingAverage) Forecast(history []float64, horizon int) []float64 {
+	window := min(max(m.Window, 1), len(history))
+	level := mean(history[len(history)-window:])
+	forecast := make([]float64, horizon)
+	for i := range forecast {
+		forecast[i] = level
+	}
+	return forecast
+}
+
+// Holt is double exponential smoothing: Alpha smooths the level and Beta the trend,
+// both between 0 and 1.
+type Holt struct {
+	Alpha float64
+	Beta  float64
+}
+
+func (m Holt) Name() string {
+	return "holt"
+}
+
+func (m Holt) Forecast(history []float64, horizon int) []float64 {
+	level, trend := history[0], 0.0
+	if len(history) > 1 {
+		trend = history[1] - history[0]
+	}
+	for _, value := range history[1:] {
+		previous := level
+		level = m.Alpha*value + (1-m.Alpha)*(level+trend)
+		trend = m.Beta*(level-previous) + (1-m.Beta)*trend
+	}
+	forecast := make([]float64, horizon)
+	for i := range forecast {
+		forecast[i] = level + float64(i+1)*trend
+	}
+	return forecast
+}
+
+// LinearTrend extends a least-squares line fitted to the last Window observations, or to
+// all of them when Window is zero.
+type LinearTrend struct {
+	Window int
+}
+
+func (m LinearTrend) Name() string {
+	return "linear"
+}
+
+func (m LinearTrend) Forecast(history []float64, horizon int) []float64 {
+	if m.Window > 0 && m.Window < len(history) {
+		history = history[len(history)-m.Window:]
+	}
+	n := float64(len(history))
+	meanX, meanY := (n-1)/2, mean(history)
+	var covariance, variance float64
+	for i, value := range history {
+		dx := float64(i) - meanX
+		covariance += dx * (value - meanY)
+		variance += dx * dx
+	}
+	slope := 0.0
+	if variance > 0 {
+		slope = covariance / variance
+	}
+	forecast := make([]float64, horizon)
+	for i := range forecast {
+		forecast[i] = meanY + slope*(n-1+float64(i+1)-meanX)
+	}
+	return forecast
+}
+
+type ForecastPoint struct {
+	Period string  `json:"period"`
+	Value  float64 `json:"value"`
+}
+
+type BacktestResult struct {
+	Model   string  `json:"model"`
+	Holdout int     `json:"holdout"`
+	MAE     float64 `json:"mae"`
+	RMSE    float64 `json:"rmse"`
+}
+
+// forecastSeries returns the business-day rates of the currency ordered by date. Weekend
+// rows only repeat Friday's rate, so they would flatten every model.
+func forecastSeries(data []Exchange, currency string) ([]Exchange, error) {
+	var series []Exchange
+	for _, exchange := range currencySeries(data, currency) {
+		if isBusinessDay(exchange.Date) {
+			series = append(series, exchange)
+		}
+	}
+	if len(series) == 0 {
+		return nil, &UnknownCurrencyError{Currency: currency}
+	}
+	return series, nil
+}
+
+func seriesRates(series []Exchange) []float64 {
+	rates := make([]float64, len(series))
+	for i, exchange := range series {
+		rates[i] = exchange.Rate
+	}
+	return rates
+}
+
+// Forecast projects the currency horizon business days past its last observation.
+func Forecast(data []Exchange, currency string, model ForecastModel, horizon int) ([]ForecastPoint, error) {
+	series, err := forecastSeries(data, currency)
+	if err != nil {
+		return nil, err
+	}
+	values := model.Forecast(seriesRates(series), horizon)
+	points := make([]ForecastPoint, len(values))
+	day := series[len(series)-1].Date
+	for i, value := range values {
+		day = nextBusinessDay(day)
+		points[i] = ForecastPoint{Period: day.Format(PeriodLayout), Value: value}
+	}
+	return points, nil
+}
+
+func nextBusinessDay(date time.Time) time.Time {
+	date = date.AddDate(0, 0, 1)
+	for !isBusinessDay(date) {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+// Backtest fits the model on all but the last holdout observations of the currency,
+// forecasts the hold-out window in one go and reports the errors against the actual rates.
+func Backtest(data []Exchange, currency string, model ForecastModel, holdout int) (BacktestResult, error) {
+	series, err := forecastSeries(data, currency)
+	if err != nil {
+		return BacktestResult{}, err
+	}
+	if holdout < 1 || holdout >= len(series) {
+		return BacktestResult{}, fmt.Errorf("hold-out window must be between 1 and %d observations, got %d", len(series)-1, holdout)
+	}
+	rates := seriesRates(series)
+	train, actual := rates[:len(rates)-holdout], rates[len(rates)-holdout:]
+	forecast := model.Forecast(train, holdout)
+
+	var absolute, squared float64
+	for i, value := range actual {
+		diff := forecast[i] - value
+		absolute += math.Abs(diff)
+		squared += diff * diff
+	}
+	return BacktestResult{
+		Model:   model.Name(),
+		Holdout: holdout,
+		MAE:     absolute / float64(holdout),
+		RMSE:    math.Sqrt(squared / float64(holdout)),
+	}, nil
+}
+
+type forecastReport struct {
+	Currency  string           `json:"currency"`
+	Model     string           `json:"model"`
+	Backtests []BacktestResult `json:"backtests"`
+	Forecast  []ForecastPoint  `json:"forecast"`
+}
+
+func runForecast(args []string) error {
+	fs := flag.NewFlagSet("forecast", flag.ExitOnError)
+	source := addDataFlags(fs)
+	currency := fs.String("currency", "USD", "Currency to forecast")
+	dates := addRangeFlags(fs)
+	modelName := fs.String("model", "all", "Model: moving-average, holt, linear, or all to use the one with the lowest backtest RMSE")
+	horizon := fs.Int("horizon", 10, "Number of business days to forecast")
+	holdout := fs.Int("holdout", 20, "Number of most recent business days held out for the backtest")
+	window := fs.Int("window", 20, "Window of the moving-average and linear models")
+	alpha := fs.Float64("alpha", 0.5, "Level smoothing of the Holt model (0-1)")
+	beta := fs.Float64("beta", 0.1, "Trend smoothing of the Holt model (0-1)")
+	format := addFormatFlag(fs)
+	fs.Parse(args)
+
+	query, err := dates.query(*currency)
+	if err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	if *horizon < 1 {
+		return errors.New("horizon must be positive")
+	}
+	if *alpha <= 0 || *alpha > 1 || *beta < 0 || *beta > 1 {
+		return errors.New("alpha must be in (0, 1] and beta in [0, 1]")
+	}
+	models := []ForecastModel{
+		MovingAverage{Window: *window},
+		Holt{Alpha: *alpha, Beta: *beta},
+		LinearTrend{Window: *window},
+	}
+	if *modelName != "all" {
+		var selected []ForecastModel
+		for _, model := range models {
+			if model.Name() == *modelName {
+				selected = append(selected, model)
+			}
+		}
+		if selected == nil {
+			return fmt.Errorf("unknown model %q (expected moving-average, holt, linear or all)", *modelName)
+		}
+		models = selected
+	}
+
+	data, err := source.load()
+	if err != nil {
+		return err
+	}
+	data = query.Filter(data)
+
+	report := forecastReport{Currency: *currency}
+	best := models[0]
+	for _, model := range models {
+		result, err := Backtest(data, *currency, model, *holdout)
+		if err != nil {
+			return err
+		}
+		if len(report.Backtests) > 0 && result.RMSE < minRMSE(report.Backtests) {
+			best = model
+		}
+		report.Backtests = append(report.Backtests, result)
+	}
+	report.Model = best.Name()
+	if report.Forecast, err = Forecast(data, *currency, best, *horizon); err != nil {
+		return err
+	}
+
+	var rows [][]string
+	for _, point := range report.Forecast {
+		rows = append(rows, []string{point.Period, report.Model, strconv.FormatFloat(point.Value, 'f', 4, 64)})
+	}
+	if *format == "table" {
+		fmt.Printf("Backtest on the last %d business days:\n", *holdout)
+		for _, result := range report.Backtests {
+			fmt.Printf("  %-16s MAE %.6f  RMSE %.6f\n", result.Model, result.MAE, result.RMSE)
+		}
+		fmt.Printf("\nForecast of %s with %s:\n", *currency, report.Model)
+	}
+	return writeRows(os.Stdout, *format, []string{"Period", "Model", "Forecast"}, rows, report)
+}
+
+func minRMSE(results []BacktestResult) float64 {
+	lowest := math.Inf(1)
+	for _, result := range results {
+		lowest = min(lowest, result.RMSE)
+	}
+	return lowest
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+type Gap struct {
+	Currency string    `json:"currency"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Days     int       `json:"days"`
+}
+
+type FillMode int
+
+const (
+	ForwardFill FillMode = iota
+	LinearInterpolation
+)
+
+func ParseFillMode(s string) (FillMode, error) {
+	switch s {
+	case "forward":
+		return ForwardFill, nil
+	case "linear":
+		return LinearInterpolation, nil
+	}
+	return 0, fmt.Errorf("unknown fill mode %q (expected forward or linear)", s)
+}
+
+func isBusinessDay(date time.Time) bool {
+	weekday := date.Weekday()
+	return weekday != time.Saturday && weekday != time.Sunday
+}
+
+// FindGaps reports runs of consecutive business days (Monday to Friday) without a rate
+// for the currency, between its first and last observation.
+func FindGaps(data []Exchange, currency string) []Gap {
+	series := currencySeries(data, currency)
+	var gaps []Gap
+	for i := 1; i < len(series); i++ {
+		var gap *Gap
+		for day := series[i-1].Date.AddDate(0, 0, 1); day.Before(series[i].Date); day = day.AddDate(0, 0, 1) {
+			if !isBusinessDay(day) {
+				continue
+			}
+			if gap == nil {
+				gaps = append(gaps, Gap{Currency: currency, Start: day})
+				gap = &gaps[len(gaps)-1]
+			}
+			gap.End = day
+			gap.Days++
+		}
+	}
+	return gaps
+}
+
+// FillDaily returns a continuous calendar-daily series for the currency from its first
+// to its last observation, filling missing days with the given mode. The dates that were
+// filled are returned alongside the series.
+func FillDaily(data []Exchange, currency string, mode FillMode) ([]Exchange, []time.Time, error) {
+	series := currencySeries(data, currency)
+	if len(series) == 0 {
+		return nil, nil, &UnknownCurrencyError{Currency: currency}
+	}
+
+	result := []Exchange{series[0]}
+	var filled []time.Time
+	for i := 1; i < len(series); i++ {
+		prev, next := series[i-1], series[i]
+		span := NewDecimal(int64(next.Date.Sub(prev.Date).Hours()/24), 0)
+		for day := prev.Date.AddDate(0, 0, 1); day.Before(next.Date); day = day.AddDate(0, 0, 1) {
+			rate := prev.Exact
+			if mode == LinearInterpolation {
+				elapsed := NewDecimal(int64(day.Sub(prev.Date).Hours()/24), 0)
+				// The loop only runs when next is at least two days after prev, so span is not zero.
+				step, _ := next.Exact.Sub(prev.Exact).Mul(elapsed).Quo(span, RateScale, RoundHalfEven)
+				rate = prev.Exact.Add(step)
+			}
+			result = append(result, Exchange{
+				Period:   day.Format(PeriodLayout),
+				Date:     day,
+				Currency: currency,
+				Rate:     rate.Float64(),
+				Exact:    rate,
+			})
+			filled = append(filled, day)
+		}
+		if next.Date.Equal(prev.Date) {
+			continue
+		}
+		result = append(result, next)
+	}
+	return result, filled, nil
+}
+
+func runGaps(args []string) error {
+	fs := flag.NewFlagSet("gaps", flag.ExitOnError)
+	source := addDataFlags(fs)
+	currency := fs.String("currency", "", "Currency to check (all currencies when empty)")
+	dates := addRangeFlags(fs)
+	fill := fs.String("fill", "", "Print a continuous daily series filled with: forward or linear")
+	format := addFormatFlag(fs)
+	fs.Parse(args)
+
+	query, err := dates.query(*currency)
+	if err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	data, err := source.load()
+	if err != nil {
+		return err
+	}
+	data = query.Filter(data)
+
+	if *fill != "" {
+		if *currency == "" {
+			return fmt.Errorf("filling requires -currency")
+		}
+		mode, err := ParseFillMode(*fill)
+		if err != nil {
+			return err
+		}
+		series, filled, err := FillDaily(data, *currency, mode)
+		if err != nil {
+			return err
+		}
+		if err := writeExchanges(os.Stdout, *format, series); err != nil {
+			return err
+		}
+		if *format == "table" {
+			fmt.Printf("\n%d rows, %d filled\n", len(series), len(filled))
+		}
+		return nil
+	}
+
+	currencies := []string{*currency}
+	if *currency == "" {
+		currencies = ListCurrencies(data)
+	}
+	gaps := []Gap{}
+	var rows [][]string
+	for _, c := range currencies {
+		for _, gap := range FindGaps(data, c) {
+			gaps = append(gaps, gap)
+			rows = append(rows, []string{gap.Currency, gap.Start.Format(PeriodLayout), gap.End.Format(PeriodLayout), strconv.Itoa(gap.Days)})
+		}
+	}
+	return writeRows(os.Stdout, *format, []string{"Currency", "Start", "End", "Missing business days"}, rows, gaps)
+}
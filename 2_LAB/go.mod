@@ -1,3 +1,3 @@
-module main
+module exchange-rates
 
 go 1.24.0
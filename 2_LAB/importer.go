@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+const FormatAuto = "auto"
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// RawRow is a single, not yet validated, exchange rate as read from the input.
+type RawRow struct {
+	Line     int
+	Period   string
+	Currency string
+	Rate     string
+}
+
+// RowSource yields raw rows one at a time and returns io.EOF at the end of the input.
+// Rows that cannot be decoded are reported with a *MalformedRowError, after which
+// reading may continue; any other error is fatal.
+type RowSource interface {
+	Next() (RawRow, error)
+}
+
+// Importer decodes one input format. Detect receives the first bytes of the input
+// with any UTF-8 BOM removed.
+type Importer interface {
+	Name() string
+	Detect(head []byte) bool
+	NewSource(r io.Reader) (RowSource, error)
+}
+
+var importers []Importer
+
+func init() {
+	RegisterImporter(rateLogImporter{})
+	RegisterImporter(xmlImporter{})
+	RegisterImporter(jsonImporter{})
+	RegisterImporter(csvImporter{})
+}
+
+// RegisterImporter adds an importer. Detection tries importers in registration order,
+// so catch-all formats must be registered last.
+func RegisterImporter(importer Importer) {
+	importers = append(importers, importer)
+}
+
+func ImporterNames() []string {
+	names := make([]string, len(importers))
+	for i, importer := range importers {
+		names[i] = importer.Name()
+	}
+	return names
+}
+
+// NewRowSource strips a leading BOM and picks the importer for format, detecting it
+// from the content when format is empty or FormatAuto.
+func NewRowSource(r io.Reader, format string) (RowSource, error) {
+	buffered := bufio.NewReader(r)
+	if head, _ := buffered.Peek(len(utf8BOM)); bytes.Equal(head, utf8BOM) {
+		buffered.Discard(len(utf8BOM))
+	}
+
+	if format != "" && format != FormatAuto {
+		for _, importer := range importers {
+			if importer.Name() == format {
+				return importer.NewSource(buffered)
+			}
+		}
+		return nil, fmt.Errorf("unknown format %q (expected %s or %s)", format, strings.Join(ImporterNames(), ", "), FormatAuto)
+	}
+
+	head, err := buffered.Peek(512)
+	if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	for _, importer := range importers {
+		if importer.Detect(head) {
+			return importer.NewSource(buffered)
+		}
+	}
+	return nil, errors.New("could not detect the input format")
+}
+
+var columnAliases = map[string][]string{
+	"period":   {"period", "date", "time", "time_period", "day"},
+	"currency": {"currency", "code", "symbol", "currency_code", "iso"},
+	"rate":     {"rate", "value", "obs_value", "price"},
+}
+
+// columnFor maps a header name to one of period, currency or rate.
+func columnFor(name string) string {
+	name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, string(utf8BOM))))
+	for column, aliases := range columnAliases {
+		for _, alias := range aliases {
+			if name == alias {
+				return column
+			}
+		}
+	}
+	return ""
+}
+
+type csvImporter struct{}
+
+func (csvImporter) Name() string {
+	return "csv"
+}
+
+func (csvImporter) Detect(head []byte) bool {
+	return true
+}
+
+func (csvImporter) NewSource(r io.Reader) (RowSource, error) {
+	buffered, ok := r.(*bufio.Reader)
+	if !ok {
+		buffered = bufio.NewReader(r)
+	}
+	head, _ := buffered.Peek(512)
+	reader := csv.NewReader(buffered)
+	reader.Comma = detectDelimiter(head)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	source := &csvSource{reader: reader, columns: map[string]int{"period": 0, "currency": 1, "rate": 2}}
+	return source, source.readHeader()
+}
+
+// detectDelimiter picks the most frequent of ';', ',' and tab in the first line.
+func detectDelimiter(head []byte) rune {
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		head = head[:i]
+	}
+	best, bestCount := ';', 0
+	for _, delimiter := range []rune{';', ',', '\t'} {
+		if count := bytes.Count(head, []byte(string(delimiter))); count > bestCount {
+			best, bestCount = delimiter, count
+		}
+	}
+	return best
+}
+
+type csvSource struct {
+	reader  *csv.Reader
+	columns map[string]int
+	pending []string
+}
+
+// readHeader maps columns by their header names. Files without a recognizable header
+// use the Period, Currency, Rate order and keep their first row as data.
+func (s *csvSource) readHeader() error {
+	header, err := s.reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		if column := columnFor(name); column != "" {
+			if _, seen := columns[column]; !seen {
+				columns[column] = i
+			}
+		}
+	}
+	if len(columns) == len(columnAliases) {
+		s.columns = columns
+		return nil
+	}
+	if len(columns) > 0 {
+		return fmt.Errorf("header %q is missing a period, currency or rate column", strings.Join(header, string(s.reader.Comma)))
+	}
+	if _, err := time.Parse(PeriodLayout, strings.TrimSpace(header[0])); err != nil {
+		// Neither a header nor data: treat it as an unknown header in the default order.
+		return nil
+	}
+	s.pending = append([]string(nil), header...)
+	return nil
+}
+
+func (s *csvSource) Next() (RawRow, error) {
+	record := s.pending
+	s.pending = nil
+	var err error
+	if record == nil {
+		record, err = s.reader.Read()
+	}
+	if err == io.EOF {
+		return RawRow{}, io.EOF
+	}
+	if err != nil {
+		line := 0
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			line = parseErr.Line
+		}
+		return RawRow{}, &MalformedRowError{Line: line, Reason: "unreadable row", Err: err}
+	}
+	line, _ := s.reader.FieldPos(0)
+	for _, i := range s.columns {
+		if i >= len(record) {
+			return RawRow{}, &MalformedRowError{Line: line, Reason: "missing columns"}
+		}
+	}
+	return RawRow{
+		Line:     line,
+		Period:   strings.TrimSpace(record[s.columns["period"]]),
+		Currency: strings.TrimSpace(record[s.columns["currency"]]),
+		Rate:     strings.TrimSpace(record[s.columns["rate"]]),
+	}, nil
+}
+
+type jsonImporter struct{}
+
+func (jsonImporter) Name() string {
+	return "json"
+}
+
+func (jsonImporter) Detect(head []byte) bool {
+	head = bytes.TrimLeft(head, " \t\r\n")
+	return len(head) > 0 && head[0] == '['
+}
+
+func (jsonImporter) NewSource(r io.Reader) (RowSource, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("could not read JSON: %w", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("JSON input must be an array of rate objects")
+	}
+	return &jsonSource{decoder: decoder}, nil
+}
+
+// jsonSource reads an array of objects such as {"date": "2019-01-02", "currency": "USD",
+// "rate": 1.1397}. The Line of its rows is the 1-based position in the array.
+type jsonSource struct {
+	decoder *json.Decoder
+	index   int
+}
+
+func (s *jsonSource) Next() (RawRow, error) {
+	if !s.decoder.More() {
+		return RawRow{}, io.EOF
+	}
+	s.index++
+	var object map[string]any
+	if err := s.decoder.Decode(&object); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return RawRow{}, &MalformedRowError{Line: s.index, Reason: "unreadable row", Err: err}
+		}
+		return RawRow{}, fmt.Errorf("could not read JSON element %d: %w", s.index, err)
+	}
+	row := RawRow{Line: s.index}
+	// Keys are visited in sorted order so that the same object always gives the same row
+	// or error; two aliases of one column, such as "date" and "time", are ambiguous.
+	keys := make(map[string]string, len(columnAliases))
+	for _, key := range slices.Sorted(maps.Keys(object)) {
+		value := object[key]
+		column := columnFor(key)
+		if other, seen := keys[column]; seen && column != "" {
+			return RawRow{}, &MalformedRowError{Line: s.index, Reason: "duplicate columns", Err: fmt.Errorf("%q and %q both name the %s column", other, key, column)}
+		}
+		keys[column] = key
+		var text string
+		switch v := value.(type) {
+		case string:
+			text = v
+		case json.Number:
+			text = v.String()
+		case nil:
+		default:
+			return RawRow{}, &MalformedRowError{Line: s.index, Reason: "unreadable row", Err: fmt.Errorf("unexpected value for %q", key)}
+		}
+		switch column {
+		case "period":
+			row.Period = text
+		case "currency":
+			row.Currency = text
+		case "rate":
+			row.Rate = text
+		}
+	}
+	if row.Period == "" || row.Currency == "" {
+		return RawRow{}, &MalformedRowError{Line: s.index, Reason: "missing columns"}
+	}
+	return row, nil
+}
+
+type xmlImporter struct{}
+
+func (xmlImporter) Name() string {
+	return "xml"
+}
+
+func (xmlImporter) Detect(head []byte) bool {
+	head = bytes.TrimLeft(head, " \t\r\n")
+	return len(head) > 0 && head[0] == '<'
+}
+
+func (xmlImporter) NewSource(r io.Reader) (RowSource, error) {
+	return &xmlSource{decoder: xml.NewDecoder(r)}, nil
+}
+
+// xmlSource reads the ECB reference rate format, where rates are nested as
+// <Cube time="2019-01-02"><Cube currency="USD" rate="1.1397"/></Cube>.
+type xmlSource struct {
+	decoder *xml.Decoder
+	period  string
+}
+
+func (s *xmlSource) Next() (RawRow, error) {
+	for {
+		token, err := s.decoder.Token()
+		if err == io.EOF {
+			return RawRow{}, io.EOF
+		}
+		if err != nil {
+			return RawRow{}, fmt.Errorf("could not read XML: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "Cube" {
+			continue
+		}
+		var period, currency, rate string
+		var hasCurrency bool
+		for _, attr := range start.Attr {
+			switch attr.Name.Local {
+			case "time":
+				period = attr.Value
+			case "currency":
+				currency, hasCurrency = attr.Value, true
+			case "rate":
+				rate = attr.Value
+			}
+		}
+		if period != "" {
+			s.period = period
+		}
+		if !hasCurrency {
+			continue
+		}
+		line, _ := s.decoder.InputPos()
+		return RawRow{Line: line, Period: s.period, Currency: currency, Rate: rate}, nil
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const ecbXML = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2020-01-03">
+			<Cube currency="USD" rate="1.1147"/>
+			<Cube currency="JPY" rate="120.52"/>
+		</Cube>
+		<Cube time="2020-01-02">
+			<Cube currency="USD" rate="1.1193"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>
+`
+
+func TestReadExchangeRatesFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		format string
+		source RowSource
+		want   []string
+	}{
+		{
+			name:   "csv",
+			input:  "Period;Currency;Rate\n2020-01-02;USD;1.1193\n2020-01-03;USD;1.1147\n",
+			source: &csvSource{},
+			want:   []string{"2020-01-02 USD 1.1193", "2020-01-03 USD 1.1147"},
+		},
+		{
+			name:   "reordered aliases",
+			input:  "Rate,Code,Date\n1.1193,USD,2020-01-02\n",
+			source: &csvSource{},
+			want:   []string{"2020-01-02 USD 1.1193"},
+		},
+		{
+			name:   "tab separated aliases",
+			input:  "TIME_PERIOD\tOBS_VALUE\tcurrency_code\n2020-01-02\t1.1193\tUSD\n",
+			source: &csvSource{},
+			want:   []string{"2020-01-02 USD 1.1193"},
+		},
+		{
+			name:   "csv with bom",
+			input:  "\ufeffPeriod;Currency;Rate\n2020-01-02;USD;1.1193\n",
+			source: &csvSource{},
+			want:   []string{"2020-01-02 USD 1.1193"},
+		},
+		{
+			name:   "headerless csv",
+			input:  "2020-01-02;USD;1.1193\n2020-01-03;USD;1.1147\n",
+			source: &csvSource{},
+			want:   []string{"2020-01-02 USD 1.1193", "2020-01-03 USD 1.1147"},
+		},
+		{
+			name:   "ecb xml",
+			input:  ecbXML,
+			source: &xmlSource{},
+			want:   []string{"2020-01-03 USD 1.1147", "2020-01-03 JPY 120.52", "2020-01-02 USD 1.1193"},
+		},
+		{
+			name:   "json numbers and strings",
+			input:  `[{"date": "2020-01-02", "currency": "USD", "rate": 1.1193}, {"Period": "2020-01-03", "Code": "USD", "Value": "1.1147"}]`,
+			source: &jsonSource{},
+			want:   []string{"2020-01-02 USD 1.1193", "2020-01-03 USD 1.1147"},
+		},
+		{
+			name:   "json with bom",
+			input:  "\ufeff\n[{\"date\": \"2020-01-02\", \"currency\": \"USD\", \"rate\": 1.1193}]",
+			source: &jsonSource{},
+			want:   []string{"2020-01-02 USD 1.1193"},
+		},
+		{
+			name:   "json missing rate",
+			input:  `[{"date": "2020-01-02", "currency": "USD", "rate": null}, {"date": "2020-01-03", "currency": "USD", "rate": 1.1147}]`,
+			source: &jsonSource{},
+			want:   []string{"2020-01-03 USD 1.1147"},
+		},
+		{
+			name:   "explicit format",
+			input:  "2020-01-02;USD;1.1193\n",
+			format: "csv",
+			source: &csvSource{},
+			want:   []string{"2020-01-02 USD 1.1193"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format := tt.format
+			if format == "" {
+				format = FormatAuto
+			}
+			source, err := NewRowSource(strings.NewReader(tt.input), format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := fmt.Sprintf("%T", source), fmt.Sprintf("%T", tt.source); got != want {
+				t.Errorf("detected %s, want %s", got, want)
+			}
+
+			data, _, err := ReadExchangeRates(strings.NewReader(tt.input), LoadOptions{Policy: FailOnInvalidRow, Format: format})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(data))
+			for i, exchange := range data {
+				got[i] = fmt.Sprintf("%s %s %v", exchange.Date.Format(PeriodLayout), exchange.Currency, exchange.Rate)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got rows\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestReadExchangeRatesRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		format string
+	}{
+		{"duplicate json aliases", `[{"date": "2020-01-02", "time": "2020-01-03", "currency": "USD", "rate": 1.1193}]`, FormatAuto},
+		{"json nested value", `[{"date": "2020-01-02", "currency": "USD", "rate": {"value": 1.1193}}]`, FormatAuto},
+		{"json without currency", `[{"date": "2020-01-02", "rate": 1.1193}]`, FormatAuto},
+		{"csv bad rate", "Period;Currency;Rate\n2020-01-02;USD;1,1193\n", FormatAuto},
+		{"wrong explicit format", "Period;Currency;Rate\n2020-01-02;USD;1.1193\n", "json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ReadExchangeRates(strings.NewReader(tt.input), LoadOptions{Policy: FailOnInvalidRow, Format: tt.format})
+			if err == nil {
+				t.Fatal("got no error")
+			}
+			if tt.format == FormatAuto && !errors.Is(err, ErrMalformedRow) {
+				t.Errorf("got %v, want a malformed row", err)
+			}
+		})
+	}
+}
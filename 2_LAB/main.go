@@ -1,128 +1,157 @@
 package main
 
 import (
-	"encoding/csv"
 	"fmt"
-	"log"
+	"io"
 	"math"
 	"os"
-	"sort"
 	"strconv"
+	"time"
 )
 
+const PeriodLayout = "2006-01-02"
+
 type Exchange struct {
-	Period   string
-	Currency string
-	Rate     float64
+	Period   string    `json:"period"`
+	Date     time.Time `json:"-"`
+	Currency string    `json:"currency"`
+	Rate     float64   `json:"rate"`
+	// Exact is the rate as written in the input. Conversions and statistics use it;
+	// Rate is its float64 approximation for sorting, plotting and anomaly scores.
+	Exact Decimal `json:"-"`
+}
+
+type RowPolicy int
+
+const (
+	// SkipInvalidRows drops malformed rows and records them in the LoadReport.
+	SkipInvalidRows RowPolicy = iota
+	// FailOnInvalidRow aborts loading with a *MalformedRowError on the first malformed row.
+	FailOnInvalidRow
+)
+
+const ReasonMissingRate = "missing rate"
+
+type LoadOptions struct {
+	Policy RowPolicy
+	// Format selects the importer by name (csv, json, xml); empty or FormatAuto detects it.
+	Format string
+}
+
+// LoadReport describes the rows dropped while loading. Rows with an empty rate are
+// expected in the ECB data (holidays) and are counted under ReasonMissingRate under
+// every policy.
+type LoadReport struct {
+	Loaded  int
+	Skipped int
+	Reasons map[string]int
+	Rows    []*MalformedRowError
 }
 
-func LoadExchangeRates(filepath string) []Exchange {
-	file, err := os.Open(filepath)
+func (r *LoadReport) skip(rowErr *MalformedRowError) {
+	r.Skipped++
+	r.Reasons[rowErr.Reason]++
+	r.Rows = append(r.Rows, rowErr)
+}
+
+func LoadExchangeRates(filepath string) ([]Exchange, error) {
+	data, _, err := LoadExchangeRatesWithOptions(filepath, LoadOptions{})
+	return data, err
+}
+
+func LoadExchangeRatesWithOptions(filepath string, opts LoadOptions) ([]Exchange, *LoadReport, error) {
+	reader, err := OpenExchangeReader(filepath, opts)
 	if err != nil {
-		log.Fatalf("Could not read file: %v", err)
+		return nil, nil, err
 	}
-	defer file.Close()
-	reader := csv.NewReader(file)
-	reader.Comma = ';'
-	records, err := reader.ReadAll()
+	defer reader.Close()
+	return collectExchangeRates(reader)
+}
+
+func ReadExchangeRates(r io.Reader, opts LoadOptions) ([]Exchange, *LoadReport, error) {
+	reader, err := NewExchangeReader(r, opts)
 	if err != nil {
-		log.Fatalf("Encountered fatal error during file reading: %v", err)
+		return nil, nil, err
 	}
+	return collectExchangeRates(reader)
+}
+
+func collectExchangeRates(reader *ExchangeReader) ([]Exchange, *LoadReport, error) {
 	var exchangeRates []Exchange
-	for i, v := range records {
-		if i == 0 || v[2] == "" {
-			continue
-		}
-		rate, err := strconv.ParseFloat(v[2], 64)
+	for exchange, err := range reader.All() {
 		if err != nil {
-			log.Printf("Warning: Could not parse rate for row %d: %v", i, err)
-			continue
+			return nil, reader.Report(), err
 		}
-		exchangeRates = append(exchangeRates, Exchange{
-			Period:   v[0],
-			Currency: v[1],
-			Rate:     rate,
-		})
+		exchangeRates = append(exchangeRates, exchange)
 	}
-	return exchangeRates
+	return exchangeRates, reader.Report(), nil
 }
 
-func SortExchangeRatesByRate(data []Exchange, ascending bool) []Exchange {
-	result := make([]Exchange, len(data))
-	copy(result, data)
-	if ascending {
-		sort.Slice(result, func(i, j int) bool {
-			return result[i].Rate < result[j].Rate
-		})
-	} else {
-		sort.Slice(result, func(i, j int) bool {
-			return result[i].Rate > result[j].Rate
-		})
+func parseRawRow(row RawRow) (Exchange, *MalformedRowError) {
+	if row.Rate == "" {
+		return Exchange{}, &MalformedRowError{Line: row.Line, Reason: ReasonMissingRate}
 	}
-	return result
-}
-
-func SortExchangeRatesByCurrency(data []Exchange, ascending bool) []Exchange {
-	result := make([]Exchange, len(data))
-	copy(result, data)
-	if ascending {
-		sort.Slice(result, func(i, j int) bool {
-			return result[i].Currency < result[j].Currency
-		})
-	} else {
-		sort.Slice(result, func(i, j int) bool {
-			return result[i].Currency > result[j].Currency
-		})
+	rate, err := strconv.ParseFloat(row.Rate, 64)
+	if err != nil {
+		return Exchange{}, &MalformedRowError{Line: row.Line, Reason: "invalid rate", Err: err}
 	}
-	return result
-}
-
-func GetCurrencyStats(data []Exchange, currency string) (float64, Exchange, Exchange) {
-	var filteredData []Exchange
-
-	for _, exchange := range data {
-		if exchange.Currency == currency {
-			filteredData = append(filteredData, exchange)
+	if err := checkRate(rate); err != nil {
+		return Exchange{}, &MalformedRowError{Line: row.Line, Reason: "invalid rate", Err: err}
+	}
+	exact, err := ParseDecimal(row.Rate)
+	if err != nil {
+		// Exponent forms such as 1.1e-3 are not plain decimal text.
+		if exact, err = DecimalFromFloat(rate); err != nil {
+			return Exchange{}, &MalformedRowError{Line: row.Line, Reason: "invalid rate", Err: err}
 		}
 	}
-
-	if len(filteredData) == 0 {
-		log.Fatalf("Currency %s not found in the data", currency)
+	date, err := time.Parse(PeriodLayout, row.Period)
+	if err != nil {
+		return Exchange{}, &MalformedRowError{Line: row.Line, Reason: "invalid period", Err: err}
 	}
+	return Exchange{
+		Period:   row.Period,
+		Date:     date,
+		Currency: row.Currency,
+		Rate:     rate,
+		Exact:    exact,
+	}, nil
+}
 
-	var sum float64
-	for _, exchange := range filteredData {
-		sum += exchange.Rate
+// checkRate rejects rates that no ratio or logarithm can use.
+func checkRate(rate float64) error {
+	if math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return ErrNonFiniteRate
 	}
-	average := sum / float64(len(filteredData))
+	if rate <= 0 {
+		return ErrNonPositiveRate
+	}
+	return nil
+}
 
-	highest := Exchange{Rate: -math.MaxFloat64}
-	lowest := Exchange{Rate: math.MaxFloat64}
+func SortExchangeRatesByRate(data []Exchange, ascending bool) []Exchange {
+	return SortExchangeRates(data, SortKey{Field: SortByRate, Descending: !ascending})
+}
 
-	for _, exchange := range filteredData {
-		if exchange.Rate > highest.Rate {
-			highest = exchange
-		}
-		if exchange.Rate < lowest.Rate {
-			lowest = exchange
-		}
-	}
+func SortExchangeRatesByCurrency(data []Exchange, ascending bool) []Exchange {
+	return SortExchangeRates(data, SortKey{Field: SortByCurrency, Descending: !ascending})
+}
 
-	return average, highest, lowest
+func SortExchangeRatesByPeriod(data []Exchange, ascending bool) []Exchange {
+	return SortExchangeRates(data, SortKey{Field: SortByPeriod, Descending: !ascending})
+}
+
+func GetCurrencyStats(data []Exchange, currency string) (float64, Exchange, Exchange, error) {
+	stats := NewStatsAccumulator(currency)
+	for _, exchange := range data {
+		stats.Add(exchange)
+	}
+	return stats.Result()
 }
 
 func main() {
-	exchangeRates := LoadExchangeRates("./euro-exchange-rates.csv")
-	average, highest, lowest := GetCurrencyStats(exchangeRates, "USD")
-	fmt.Printf("Average Rate: %.4f\n", average)
-	fmt.Printf("Highest Rate: %.4f (on %s)\n", highest.Rate, highest.Period)
-	fmt.Printf("Lowest Rate: %.4f (on %s)\n", lowest.Rate, lowest.Period)
-
-	// fmt.Println("\nSorted by Rate (Ascending):")
-	// rateAscending := SortExchangeRatesByRate(exchangeRates, true)
-	// fmt.Println(rateAscending)
-	//
-	// fmt.Println("\nSorted by Rate (Descending):")
-	// rateDescending := SortExchangeRatesByRate(exchangeRates, false)
-	// fmt.Println(rateDescending)
+	if err := runCLI(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 }
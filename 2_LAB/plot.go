@@ -0,0 +1,349 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	UnicodeSparkChars = []rune("▁▂▃▄▅▆▇█")
+	ASCIISparkChars   = []rune("_.-:=+*#")
+)
+
+// plotColors are used for the series of a PNG plot in order.
+var plotColors = []color.RGBA{
+	{R: 0x1f, G: 0x77, B: 0xb4, A: 0xff},
+	{R: 0xd6, G: 0x27, B: 0x28, A: 0xff},
+	{R: 0x2c, G: 0xa0, B: 0x2c, A: 0xff},
+	{R: 0xff, G: 0x7f, B: 0x0e, A: 0xff},
+	{R: 0x94, G: 0x67, B: 0xbd, A: 0xff},
+	{R: 0x8c, G: 0x56, B: 0x4b, A: 0xff},
+}
+
+var plotColorNames = []string{"blue", "red", "green", "orange", "purple", "brown"}
+
+// PlotSeries is the rate history of one currency, ordered by date, together with the
+// markers drawn on top of it.
+type PlotSeries struct {
+	Currency string
+	Points   []SeriesPoint
+	Average  float64
+	Highest  SeriesPoint
+	Lowest   SeriesPoint
+}
+
+// NewPlotSeries builds the series of a currency with its markers taken from GetCurrencyStats.
+func NewPlotSeries(data []Exchange, currency string) (PlotSeries, error) {
+	series := currencySeries(data, currency)
+	average, highest, lowest, err := GetCurrencyStats(series, currency)
+	if err != nil {
+		return PlotSeries{}, err
+	}
+	points := make([]SeriesPoint, len(series))
+	for i, exchange := range series {
+		points[i] = exchangePoint(exchange)
+	}
+	return PlotSeries{
+		Currency: currency,
+		Points:   points,
+		Average:  average,
+		Highest:  exchangePoint(highest),
+		Lowest:   exchangePoint(lowest),
+	}, nil
+}
+
+func exchangePoint(exchange Exchange) SeriesPoint {
+	return SeriesPoint{Period: exchange.Period, Date: exchange.Date, Value: exchange.Rate}
+}
+
+// Normalize rebases the series so that its first point on or after start is 100.
+func (s PlotSeries) Normalize(start time.Time) (PlotSeries, error) {
+	var base float64
+	for _, point := range s.Points {
+		if !point.Date.Before(start) {
+			base = point.Value
+			break
+		}
+	}
+	if base == 0 {
+		return PlotSeries{}, fmt.Errorf("%w: %s on or after %s", ErrRateNotFound, s.Currency, start.Format(PeriodLayout))
+	}
+	scale := func(point SeriesPoint) SeriesPoint {
+		point.Value = point.Value / base * 100
+		return point
+	}
+	normalized := PlotSeries{
+		Currency: s.Currency,
+		Points:   make([]SeriesPoint, len(s.Points)),
+		Average:  s.Average / base * 100,
+		Highest:  scale(s.Highest),
+		Lowest:   scale(s.Lowest),
+	}
+	for i, point := range s.Points {
+		normalized.Points[i] = scale(point)
+	}
+	return normalized, nil
+}
+
+// valueRange returns the smallest and largest value over all series.
+func valueRange(series []PlotSeries) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		lo = min(lo, s.Lowest.Value)
+		hi = max(hi, s.Highest.Value)
+	}
+	return lo, hi
+}
+
+// dateRange returns the first and last date over all series.
+func dateRange(series []PlotSeries) (time.Time, time.Time) {
+	var first, last time.Time
+	for _, s := range series {
+		if len(s.Points) == 0 {
+			continue
+		}
+		if start := s.Points[0].Date; first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if end := s.Points[len(s.Points)-1].Date; end.After(last) {
+			last = end
+		}
+	}
+	return first, last
+}
+
+// sparkColumn returns the column of a width-wide sparkline that holds point i of n.
+func sparkColumn(i, n, width int) int {
+	if n <= width {
+		return i
+	}
+	return i * width / n
+}
+
+// Sparkline draws values as a single line of chars scaled between lo and hi. When there
+// are more values than width, every column shows the average of its values.
+func Sparkline(values []float64, width int, chars []rune, lo, hi float64) string {
+	columns := min(len(values), width)
+	sums := make([]float64, columns)
+	counts := make([]int, columns)
+	for i, value := range values {
+		column := sparkColumn(i, len(values), width)
+		sums[column] += value
+		counts[column]++
+	}
+
+	var b strings.Builder
+	for column := range sums {
+		level := len(chars) / 2
+		if hi > lo {
+			level = int((sums[column]/float64(counts[column])-lo)/(hi-lo)*float64(len(chars)-1) + 0.5)
+		}
+		b.WriteRune(chars[max(0, min(level, len(chars)-1))])
+	}
+	return b.String()
+}
+
+// WriteSparklines prints every series as a sparkline with a marker row below it showing
+// where the highest (^) and lowest (v) rates fall. Normalized series share one scale so
+// they can be compared; otherwise each series uses its own range.
+func WriteSparklines(w io.Writer, series []PlotSeries, width int, chars []rune, shared bool) {
+	sharedLo, sharedHi := valueRange(series)
+	for i, s := range series {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		lo, hi := s.Lowest.Value, s.Highest.Value
+		if shared {
+			lo, hi = sharedLo, sharedHi
+		}
+		values := make([]float64, len(s.Points))
+		index := make(map[string]int, len(s.Points))
+		for j, point := range s.Points {
+			values[j] = point.Value
+			index[point.Period] = j
+		}
+		line := Sparkline(values, width, chars, lo, hi)
+
+		markers := []rune(strings.Repeat(" ", len([]rune(line))))
+		markers[sparkColumn(index[s.Lowest.Period], len(values), width)] = 'v'
+		markers[sparkColumn(index[s.Highest.Period], len(values), width)] = '^'
+
+		fmt.Fprintf(w, "%s  %s .. %s\n", s.Currency, s.Points[0].Period, s.Points[len(s.Points)-1].Period)
+		fmt.Fprintln(w, line)
+		fmt.Fprintln(w, strings.TrimRight(string(markers), " "))
+		fmt.Fprintf(w, "^ max %.4f (%s)  v min %.4f (%s)  avg %.4f\n",
+			s.Highest.Value, s.Highest.Period, s.Lowest.Value, s.Lowest.Period, s.Average)
+	}
+}
+
+// WritePNG draws the series as lines on a white width x height image. The highest and
+// lowest rate of every series are marked with a square and its average with a dashed
+// line in the series color.
+func WritePNG(w io.Writer, series []PlotSeries, width, height int) error {
+	const margin = 20
+	if width <= 2*margin || height <= 2*margin {
+		return fmt.Errorf("image size %dx%d is too small", width, height)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	first, last := dateRange(series)
+	lo, hi := valueRange(series)
+	if hi == lo {
+		lo, hi = lo-1, hi+1
+	}
+	span := last.Sub(first).Hours()
+	x := func(date time.Time) int {
+		if span == 0 {
+			return width / 2
+		}
+		return margin + int(date.Sub(first).Hours()/span*float64(width-2*margin-1)+0.5)
+	}
+	y := func(value float64) int {
+		return height - margin - 1 - int((value-lo)/(hi-lo)*float64(height-2*margin-1)+0.5)
+	}
+
+	axis := color.RGBA{R: 0x99, G: 0x99, B: 0x99, A: 0xff}
+	drawLine(img, margin, margin, margin, height-margin-1, axis)
+	drawLine(img, margin, height-margin-1, width-margin-1, height-margin-1, axis)
+
+	for i, s := range series {
+		c := plotColors[i%len(plotColors)]
+		avgY := y(s.Average)
+		for px := margin; px < width-margin; px++ {
+			if (px/4)%2 == 0 {
+				img.SetRGBA(px, avgY, c)
+			}
+		}
+		for j := 1; j < len(s.Points); j++ {
+			prev, point := s.Points[j-1], s.Points[j]
+			drawLine(img, x(prev.Date), y(prev.Value), x(point.Date), y(point.Value), c)
+		}
+		for _, marker := range []SeriesPoint{s.Highest, s.Lowest} {
+			mx, my := x(marker.Date), y(marker.Value)
+			for dx := -3; dx <= 3; dx++ {
+				for dy := -3; dy <= 3; dy++ {
+					img.SetRGBA(mx+dx, my+dy, c)
+				}
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// drawLine draws a one pixel wide line using Bresenham's algorithm.
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	for {
+		img.SetRGBA(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func runPlot(args []string) error {
+	fs := flag.NewFlagSet("plot", flag.ExitOnError)
+	source := addDataFlags(fs)
+	currencyList := fs.String("currency", "USD", "Currency to plot, or a comma-separated list of currencies")
+	dates := addRangeFlags(fs)
+	normalize := fs.String("normalize", "", "Rebase every currency to 100 at this period in YYYY-MM-DD format (first rate on or after it)")
+	width := fs.Int("width", 72, "Width of the terminal sparkline in characters")
+	ascii := fs.Bool("ascii", false, "Draw the sparkline with ASCII characters only")
+	output := fs.String("out", "", "Also save the plot as a PNG to this file")
+	pngWidth := fs.Int("png-width", 960, "Width of the PNG in pixels")
+	pngHeight := fs.Int("png-height", 480, "Height of the PNG in pixels")
+	fs.Parse(args)
+
+	query, err := dates.query("")
+	if err != nil {
+		return err
+	}
+	var start time.Time
+	if *normalize != "" {
+		if start, err = ParsePeriod(*normalize); err != nil {
+			return err
+		}
+	}
+	if *width < 1 {
+		return fmt.Errorf("width must be positive")
+	}
+
+	data, err := source.load()
+	if err != nil {
+		return err
+	}
+	data = query.Filter(data)
+
+	var series []PlotSeries
+	for _, currency := range strings.Split(*currencyList, ",") {
+		s, err := NewPlotSeries(data, strings.TrimSpace(currency))
+		if err != nil {
+			return err
+		}
+		if *normalize != "" {
+			if s, err = s.Normalize(start); err != nil {
+				return err
+			}
+		}
+		series = append(series, s)
+	}
+
+	chars := UnicodeSparkChars
+	if *ascii {
+		chars = ASCIISparkChars
+	}
+	WriteSparklines(os.Stdout, series, *width, chars, *normalize != "")
+	if *output == "" {
+		return nil
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := WritePNG(f, series, *pngWidth, *pngHeight); err != nil {
+		return err
+	}
+	legend := make([]string, len(series))
+	for i, s := range series {
+		legend[i] = s.Currency + " " + plotColorNames[i%len(plotColorNames)]
+	}
+	fmt.Printf("\nPlot saved to: %s (%s)\n", *output, strings.Join(legend, ", "))
+	return nil
+}
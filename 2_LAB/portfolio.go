@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Holding sets the balance of a currency from Date onwards, until the next holding of
+// the same currency.
+type Holding struct {
+	Date     time.Time
+	Currency string
+	Amount   Decimal
+}
+
+// ParseHoldings reads Period, Currency, Amount rows separated by ';', ',' or tabs. A
+// header row is optional.
+func ParseHoldings(r io.Reader) ([]Holding, error) {
+	buffered := bufio.NewReader(r)
+	head, _ := buffered.Peek(512)
+	reader := csv.NewReader(buffered)
+	reader.Comma = detectDelimiter(head)
+	reader.FieldsPerRecord = -1
+
+	var holdings []Holding
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) < 3 {
+			return nil, fmt.Errorf("holdings line %d: expected period, currency and amount", line)
+		}
+		date, err := ParsePeriod(strings.TrimSpace(record[0]))
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("holdings line %d: %w", line, err)
+		}
+		amount, err := ParseDecimal(record[2])
+		if err != nil {
+			return nil, fmt.Errorf("holdings line %d: %w", line, err)
+		}
+		holdings = append(holdings, Holding{Date: date, Currency: strings.TrimSpace(record[1]), Amount: amount})
+	}
+	if len(holdings) == 0 {
+		return nil, errors.New("no holdings")
+	}
+	return holdings, nil
+}
+
+// ParseHoldingList parses constant holdings such as "USD=1000,GBP=250.50", held from the
+// given date.
+func ParseHoldingList(s string, from time.Time) ([]Holding, error) {
+	var holdings []Holding
+	for _, part := range strings.Split(s, ",") {
+		currency, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid holding %q, expected CURRENCY=AMOUNT", part)
+		}
+		amount, err := ParseDecimal(value)
+		if err != nil {
+			return nil, err
+		}
+		holdings = append(holdings, Holding{Date: from, Currency: currency, Amount: amount})
+	}
+	return holdings, nil
+}
+
+// Valuation is the value of the portfolio on one day. Flow is the value of the balance
+// changes that took effect on that day.
+type Valuation struct {
+	Period string  `json:"period"`
+	Value  Decimal `json:"value"`
+	Flow   Decimal `json:"flow"`
+}
+
+type PortfolioSummary struct {
+	Currency   string    `json:"currency"`
+	Start      Valuation `json:"start"`
+	End        Valuation `json:"end"`
+	Highest    Valuation `json:"highest"`
+	Lowest     Valuation `json:"lowest"`
+	NetFlows   Decimal   `json:"net_flows"`
+	ProfitLoss Decimal   `json:"profit_loss"`
+	Return     float64   `json:"return"`
+}
+
+// ValuePortfolio values the holdings in the target currency on every date of the data
+// from the first holding onwards, using the latest rates on or before each date. Every
+// position is converted exactly and rounded to the minor units of the target currency.
+func ValuePortfolio(data []Exchange, holdings []Holding, target string, mode RoundingMode) ([]Valuation, error) {
+	if len(holdings) == 0 {
+		return nil, errors.New("no holdings")
+	}
+	holdings = append([]Holding(nil), holdings...)
+	sort.SliceStable(holdings, func(i, j int) bool {
+		return holdings[i].Date.Before(holdings[j].Date)
+	})
+	store := NewRateStore(data)
+
+	seen := make(map[time.Time]bool)
+	var dates []time.Time
+	for _, exchange := range data {
+		if !seen[exchange.Date] && !exchange.Date.Before(holdings[0].Date) {
+			seen[exchange.Date] = true
+			dates = append(dates, exchange.Date)
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+
+	value := func(currency string, amount Decimal, date time.Time) (Decimal, error) {
+		converted, _, err := store.Convert(Money{Amount: amount, Currency: currency}, target, date, mode)
+		return converted.Amount, err
+	}
+
+	zero := NewDecimal(0, MinorUnits(target))
+	balances := make(map[string]Decimal)
+	var currencies []string
+	valuations := make([]Valuation, 0, len(dates))
+	next := 0
+	for _, date := range dates {
+		valuation := Valuation{Period: date.Format(PeriodLayout), Value: zero, Flow: zero}
+		for ; next < len(holdings) && !holdings[next].Date.After(date); next++ {
+			holding := holdings[next]
+			previous, held := balances[holding.Currency]
+			if !held {
+				currencies = append(currencies, holding.Currency)
+				sort.Strings(currencies)
+			}
+			flow, err := value(holding.Currency, holding.Amount.Sub(previous), date)
+			if err != nil {
+				return nil, err
+			}
+			valuation.Flow = valuation.Flow.Add(flow)
+			balances[holding.Currency] = holding.Amount
+		}
+		for _, currency := range currencies {
+			converted, err := value(currency, balances[currency], date)
+			if err != nil {
+				return nil, err
+			}
+			valuation.Value = valuation.Value.Add(converted)
+		}
+		valuations = append(valuations, valuation)
+	}
+	return valuations, nil
+}
+
+// SummarizePortfolio reports the profit or loss between the first and last valuation.
+// Balance changes after the first day are flows, not profit, so the profit is the change
+// in value minus the net flows, and the return is relative to the capital invested.
+func SummarizePortfolio(valuations []Valuation, currency string) (PortfolioSummary, error) {
+	if len(valuations) == 0 {
+		return PortfolioSummary{}, errors.New("no valuations")
+	}
+	summary := PortfolioSummary{
+		Currency: currency,
+		Start:    valuations[0],
+		End:      valuations[len(valuations)-1],
+		Highest:  valuations[0],
+		Lowest:   valuations[0],
+		NetFlows: NewDecimal(0, MinorUnits(currency)),
+	}
+	for _, valuation := range valuations[1:] {
+		summary.NetFlows = summary.NetFlows.Add(valuation.Flow)
+		if valuation.Value.Cmp(summary.Highest.Value) > 0 {
+			summary.Highest = valuation
+		}
+		if valuation.Value.Cmp(summary.Lowest.Value) < 0 {
+			summary.Lowest = valuation
+		}
+	}
+	summary.ProfitLoss = summary.End.Value.Sub(summary.Start.Value).Sub(summary.NetFlows)
+	if invested := summary.Start.Value.Add(summary.NetFlows); invested.Sign() > 0 {
+		summary.Return = summary.ProfitLoss.Float64() / invested.Float64()
+	}
+	return summary, nil
+}
+
+type portfolioReport struct {
+	Summary PortfolioSummary `json:"summary"`
+	Series  []Valuation      `json:"series"`
+}
+
+func runPortfolio(args []string) error {
+	fs := flag.NewFlagSet("portfolio", flag.ExitOnError)
+	source := addDataFlags(fs)
+	holdingsFile := fs.String("holdings", "", "File of Period;Currency;Amount rows setting balances from a date onwards")
+	holdingList := fs.String("hold", "", "Constant holdings held from -from, such as USD=1000,GBP=250")
+	target := fs.String("in", BaseCurrency, "Currency to value the portfolio in")
+	dates := addRangeFlags(fs)
+	series := fs.Bool("series", false, "Print the value on every date, not only the summary")
+	rounding := addRoundingFlag(fs)
+	format := addFormatFlag(fs)
+	fs.Parse(args)
+
+	query, err := dates.query("")
+	if err != nil {
+		return err
+	}
+	mode, err := ParseRoundingMode(*rounding)
+	if err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+
+	var holdings []Holding
+	switch {
+	case *holdingsFile != "" && *holdingList != "":
+		return errors.New("use either -holdings or -hold")
+	case *holdingsFile != "":
+		f, err := openDataFile(*holdingsFile)
+		if err != nil {
+			return err
+		}
+		holdings, err = ParseHoldings(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", *holdingsFile, err)
+		}
+	case *holdingList != "":
+		if holdings, err = ParseHoldingList(*holdingList, query.From); err != nil {
+			return err
+		}
+	default:
+		return errors.New("holdings are required, use -holdings or -hold")
+	}
+
+	data, err := source.load()
+	if err != nil {
+		return err
+	}
+	// Rates before -from are still needed to value the first days of the range.
+	valuations, err := ValuePortfolio(ExchangeQuery{To: query.To}.Filter(data), holdings, *target, mode)
+	if err != nil {
+		return err
+	}
+	if !query.From.IsZero() {
+		from := query.From.Format(PeriodLayout)
+		start := sort.Search(len(valuations), func(i int) bool {
+			return valuations[i].Period >= from
+		})
+		valuations = valuations[start:]
+	}
+	summary, err := SummarizePortfolio(valuations, *target)
+	if err != nil {
+		return err
+	}
+
+	var rows [][]string
+	for _, valuation := range valuations {
+		rows = append(rows, []string{valuation.Period, valuation.Value.String(), valuation.Flow.String()})
+	}
+	switch {
+	case *format == "json":
+		report := portfolioReport{Summary: summary}
+		if *series {
+			report.Series = valuations
+		}
+		return writeRows(os.Stdout, *format, nil, nil, report)
+	case *format == "csv" && *series:
+		return writeRows(os.Stdout, *format, []string{"Period", "Value", "Flow"}, rows, nil)
+	case *format == "csv":
+		rows = [][]string{
+			{"start", summary.Start.Period, summary.Start.Value.String()},
+			{"end", summary.End.Period, summary.End.Value.String()},
+			{"highest", summary.Highest.Period, summary.Highest.Value.String()},
+			{"lowest", summary.Lowest.Period, summary.Lowest.Value.String()},
+			{"net_flows", "", summary.NetFlows.String()},
+			{"profit_loss", "", summary.ProfitLoss.String()},
+		}
+		return writeRows(os.Stdout, *format, []string{"Statistic", "Period", "Value"}, rows, nil)
+	}
+
+	if *series {
+		if err := writeRows(os.Stdout, *format, []string{"Period", "Value", "Flow"}, rows, nil); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+	fmt.Printf("Start:       %s %s (on %s)\n", summary.Start.Value, *target, summary.Start.Period)
+	fmt.Printf("End:         %s %s (on %s)\n", summary.End.Value, *target, summary.End.Period)
+	fmt.Printf("Highest:     %s %s (on %s)\n", summary.Highest.Value, *target, summary.Highest.Period)
+	fmt.Printf("Lowest:      %s %s (on %s)\n", summary.Lowest.Value, *target, summary.Lowest.Period)
+	fmt.Printf("Net Flows:   %s %s\n", summary.NetFlows, *target)
+	fmt.Printf("Profit/Loss: %s %s (%.2f%%)\n", summary.ProfitLoss, *target, summary.Return*100)
+	return nil
+}
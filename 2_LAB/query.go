@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+type ExchangeQuery struct {
+	Currency string
+	From     time.Time
+	To       time.Time
+	Weekdays []time.Weekday
+	Months   []time.Month
+	Years    []int
+}
+
+func ParsePeriod(period string) (time.Time, error) {
+	date, err := time.Parse(PeriodLayout, period)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid period %q, expected YYYY-MM-DD: %w", period, err)
+	}
+	return date, nil
+}
+
+// Quarter returns a query covering the given quarter (1-4) of a year.
+func Quarter(year, quarter int) ExchangeQuery {
+	from := time.Date(year, time.Month(3*(quarter-1)+1), 1, 0, 0, 0, 0, time.UTC)
+	return ExchangeQuery{
+		From: from,
+		To:   from.AddDate(0, 3, -1),
+	}
+}
+
+func (q ExchangeQuery) ForCurrency(currency string) ExchangeQuery {
+	q.Currency = currency
+	return q
+}
+
+func (q ExchangeQuery) Matches(exchange Exchange) bool {
+	if q.Currency != "" && exchange.Currency != q.Currency {
+		return false
+	}
+	if !q.From.IsZero() && exchange.Date.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && exchange.Date.After(q.To) {
+		return false
+	}
+	if len(q.Weekdays) > 0 && !slices.Contains(q.Weekdays, exchange.Date.Weekday()) {
+		return false
+	}
+	if len(q.Months) > 0 && !slices.Contains(q.Months, exchange.Date.Month()) {
+		return false
+	}
+	if len(q.Years) > 0 && !slices.Contains(q.Years, exchange.Date.Year()) {
+		return false
+	}
+	return true
+}
+
+func (q ExchangeQuery) Filter(data []Exchange) []Exchange {
+	var result []Exchange
+	for _, exchange := range data {
+		if q.Matches(exchange) {
+			result = append(result, exchange)
+		}
+	}
+	return result
+}
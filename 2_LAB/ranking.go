@@ -0,0 +1,236 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type SortField int
+
+const (
+	SortByCurrency SortField = iota
+	SortByPeriod
+	SortByRate
+)
+
+func (f SortField) String() string {
+	switch f {
+	case SortByCurrency:
+		return "currency"
+	case SortByPeriod:
+		return "period"
+	case SortByRate:
+		return "rate"
+	}
+	return fmt.Sprintf("SortField(%d)", int(f))
+}
+
+func ParseSortField(s string) (SortField, error) {
+	for _, field := range []SortField{SortByCurrency, SortByPeriod, SortByRate} {
+		if field.String() == s {
+			return field, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown sort key %q (expected rate, currency or period)", s)
+}
+
+type SortKey struct {
+	Field      SortField
+	Descending bool
+}
+
+// ParseSortKeys parses a comma-separated list such as "currency,period,rate:desc". Keys
+// without an :asc or :desc suffix use the given default direction.
+func ParseSortKeys(s string, descending bool) ([]SortKey, error) {
+	var keys []SortKey
+	for _, part := range strings.Split(s, ",") {
+		name, direction, _ := strings.Cut(strings.TrimSpace(part), ":")
+		field, err := ParseSortField(name)
+		if err != nil {
+			return nil, err
+		}
+		key := SortKey{Field: field, Descending: descending}
+		switch direction {
+		case "":
+		case "asc":
+			key.Descending = false
+		case "desc":
+			key.Descending = true
+		default:
+			return nil, fmt.Errorf("unknown sort direction %q (expected asc or desc)", direction)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (k SortKey) compare(a, b Exchange) int {
+	var c int
+	switch k.Field {
+	case SortByCurrency:
+		c = cmp.Compare(a.Currency, b.Currency)
+	case SortByPeriod:
+		c = cmp.Compare(a.Period, b.Period)
+	case SortByRate:
+		c = cmp.Compare(a.Rate, b.Rate)
+	}
+	if k.Descending {
+		return -c
+	}
+	return c
+}
+
+// CompareExchanges returns a comparison function ordering by the keys in turn.
+func CompareExchanges(keys ...SortKey) func(a, b Exchange) int {
+	return func(a, b Exchange) int {
+		for _, key := range keys {
+			if c := key.compare(a, b); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+}
+
+// SortExchangeRates returns a copy of data ordered by the keys. The sort is stable, so
+// rows that compare equal on every key keep their original order.
+func SortExchangeRates(data []Exchange, keys ...SortKey) []Exchange {
+	result := slices.Clone(data)
+	slices.SortStableFunc(result, CompareExchanges(keys...))
+	return result
+}
+
+type GroupField int
+
+const (
+	GroupByCurrency GroupField = iota
+	GroupByYear
+	GroupByQuarter
+	GroupByMonth
+)
+
+func (f GroupField) String() string {
+	switch f {
+	case GroupByCurrency:
+		return "currency"
+	case GroupByYear:
+		return "year"
+	case GroupByQuarter:
+		return "quarter"
+	case GroupByMonth:
+		return "month"
+	}
+	return fmt.Sprintf("GroupField(%d)", int(f))
+}
+
+// ParseGroupFields parses a comma-separated list such as "currency,year". An empty
+// string means no grouping.
+func ParseGroupFields(s string) ([]GroupField, error) {
+	if s == "" {
+		return nil, nil
+	}
+	all := []GroupField{GroupByCurrency, GroupByYear, GroupByQuarter, GroupByMonth}
+	var fields []GroupField
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		i := slices.IndexFunc(all, func(f GroupField) bool {
+			return f.String() == name
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("unknown group %q (expected currency, year, quarter or month)", name)
+		}
+		fields = append(fields, all[i])
+	}
+	return fields, nil
+}
+
+// compare orders two rows by the group they fall into. Quarters and months are calendar
+// periods, so they include the year.
+func (f GroupField) compare(a, b Exchange) int {
+	switch f {
+	case GroupByCurrency:
+		return cmp.Compare(a.Currency, b.Currency)
+	case GroupByYear:
+		return cmp.Compare(a.Date.Year(), b.Date.Year())
+	case GroupByQuarter:
+		return cmp.Or(cmp.Compare(a.Date.Year(), b.Date.Year()), cmp.Compare((a.Date.Month()-1)/3, (b.Date.Month()-1)/3))
+	case GroupByMonth:
+		return cmp.Or(cmp.Compare(a.Date.Year(), b.Date.Year()), cmp.Compare(a.Date.Month(), b.Date.Month()))
+	}
+	return 0
+}
+
+// Ranking orders exchange rates by several keys. When Group is set, the rows are
+// ordered group by group, and Top and Bottom keep only the first and last rows of
+// every group. BusinessDays drops weekend rows, which repeat Friday's rate and would
+// otherwise take three slots of a ranking for a single week.
+//
+// Rates are units of the currency per euro, so the 10 weakest USD days of each year
+// have the highest rates:
+//
+//	Ranking{
+//		Keys:         []SortKey{{Field: SortByRate, Descending: true}},
+//		Group:        []GroupField{GroupByYear},
+//		Top:          10,
+//		BusinessDays: true,
+//	}
+//
+// applied to the USD rows.
+type Ranking struct {
+	Keys         []SortKey
+	Group        []GroupField
+	Top          int
+	Bottom       int
+	BusinessDays bool
+}
+
+func (r Ranking) sameGroup(a, b Exchange) bool {
+	for _, field := range r.Group {
+		if field.compare(a, b) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns a sorted copy of data, without weekend rows when BusinessDays is set,
+// limited to the Top and Bottom rows of every group when either is positive.
+func (r Ranking) Apply(data []Exchange) []Exchange {
+	byKeys := CompareExchanges(r.Keys...)
+	result := slices.Clone(data)
+	if r.BusinessDays {
+		result = slices.DeleteFunc(result, func(exchange Exchange) bool {
+			return !isBusinessDay(exchange.Date)
+		})
+	}
+	slices.SortStableFunc(result, func(a, b Exchange) int {
+		for _, field := range r.Group {
+			if c := field.compare(a, b); c != 0 {
+				return c
+			}
+		}
+		return byKeys(a, b)
+	})
+	if r.Top <= 0 && r.Bottom <= 0 {
+		return result
+	}
+
+	var limited []Exchange
+	for start := 0; start < len(result); {
+		end := start + 1
+		for end < len(result) && r.sameGroup(result[start], result[end]) {
+			end++
+		}
+		group := result[start:end]
+		if r.Top+r.Bottom >= len(group) {
+			limited = append(limited, group...)
+		} else {
+			limited = append(limited, group[:max(r.Top, 0)]...)
+			limited = append(limited, group[len(group)-max(r.Bottom, 0):]...)
+		}
+		start = end
+	}
+	return limited
+}
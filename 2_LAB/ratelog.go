@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+const DefaultStoreFile = "./exchange-rates.db"
+
+// A rate log is an append-only binary file: an 8 byte header followed by fixed-size
+// records of the day (int32 days since 1970-01-01), the currency code (up to 4 bytes,
+// zero padded) and the rate (float64 bits), all little-endian.
+const (
+	rateLogMagic      = "EXRATES\x01"
+	rateLogRecordSize = 16
+
+	// reasonTruncatedRecord marks a record cut short by an interrupted append.
+	reasonTruncatedRecord = "truncated record"
+)
+
+var ErrInvalidRateLog = errors.New("invalid rate log")
+
+type rateLogKey struct {
+	day      int32
+	currency [4]byte
+}
+
+func encodeRateLogKey(exchange Exchange) (rateLogKey, error) {
+	key := rateLogKey{day: int32(exchange.Date.Unix() / 86400)}
+	if exchange.Currency == "" || len(exchange.Currency) > len(key.currency) {
+		return key, fmt.Errorf("currency %q cannot be stored, expected a code of 1 to %d bytes", exchange.Currency, len(key.currency))
+	}
+	copy(key.currency[:], exchange.Currency)
+	return key, nil
+}
+
+func appendRateLogRecord(buf []byte, key rateLogKey, rate float64) []byte {
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(key.day))
+	buf = append(buf, key.currency[:]...)
+	return binary.LittleEndian.AppendUint64(buf, math.Float64bits(rate))
+}
+
+func decodeRateLogRecord(record []byte) Exchange {
+	day := int32(binary.LittleEndian.Uint32(record[0:4]))
+	date := time.Unix(int64(day)*86400, 0).UTC()
+	return Exchange{
+		Period:   date.Format(PeriodLayout),
+		Date:     date,
+		Currency: string(bytes.TrimRight(record[4:8], "\x00")),
+		Rate:     math.Float64frombits(binary.LittleEndian.Uint64(record[8:16])),
+	}
+}
+
+type rateLogImporter struct{}
+
+func (rateLogImporter) Name() string {
+	return "store"
+}
+
+func (rateLogImporter) Detect(head []byte) bool {
+	return bytes.HasPrefix(head, []byte(rateLogMagic))
+}
+
+func (rateLogImporter) NewSource(r io.Reader) (RowSource, error) {
+	header := make([]byte, len(rateLogMagic))
+	if _, err := io.ReadFull(r, header); err != nil || string(header) != rateLogMagic {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidRateLog)
+	}
+	return &rateLogSource{reader: bufio.NewReaderSize(r, 64*1024)}, nil
+}
+
+// rateLogSource decodes records straight into exchanges. The Line of its rows is the
+// 1-based record number.
+type rateLogSource struct {
+	reader *bufio.Reader
+	record [rateLogRecordSize]byte
+	index  int
+}
+
+func (s *rateLogSource) readRecord() error {
+	n, err := io.ReadFull(s.reader, s.record[:])
+	if err == io.EOF {
+		return io.EOF
+	}
+	s.index++
+	if err == io.ErrUnexpectedEOF {
+		return &MalformedRowError{Line: s.index, Reason: reasonTruncatedRecord, Err: fmt.Errorf("%d of %d bytes", n, rateLogRecordSize)}
+	}
+	return err
+}
+
+// NextExchange rejects the rates that parseRawRow would reject, so a damaged log is
+// read like a damaged text file.
+func (s *rateLogSource) NextExchange() (Exchange, error) {
+	if err := s.readRecord(); err != nil {
+		return Exchange{}, err
+	}
+	exchange := decodeRateLogRecord(s.record[:])
+	if err := checkRate(exchange.Rate); err != nil {
+		return Exchange{}, &MalformedRowError{Line: s.index, Reason: "invalid rate", Err: err}
+	}
+	exact, err := DecimalFromFloat(exchange.Rate)
+	if err != nil {
+		return Exchange{}, &MalformedRowError{Line: s.index, Reason: "invalid rate", Err: err}
+	}
+	exchange.Exact = exact
+	return exchange, nil
+}
+
+func (s *rateLogSource) Next() (RawRow, error) {
+	if err := s.readRecord(); err != nil {
+		return RawRow{}, err
+	}
+	exchange := decodeRateLogRecord(s.record[:])
+	return RawRow{
+		Line:     s.index,
+		Period:   exchange.Period,
+		Currency: exchange.Currency,
+		Rate:     strconv.FormatFloat(exchange.Rate, 'f', -1, 64),
+	}, nil
+}
+
+type AppendResult struct {
+	Appended   int
+	Duplicates int
+	// Conflicts are the duplicates whose rate differs from the stored one. The stored
+	// rate is kept.
+	Conflicts []Exchange
+}
+
+// AppendToRateLog appends the rows whose (period, currency) is not in the log yet,
+// creating the log if needed. Existing records are never rewritten; a partial record
+// left by an interrupted write is cut off before appending.
+func AppendToRateLog(path string, rows []Exchange) (AppendResult, error) {
+	var result AppendResult
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return result, err
+	}
+	defer file.Close()
+
+	stored, end, err := readRateLogKeys(file)
+	if err != nil {
+		return result, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var buf []byte
+	if end == 0 {
+		buf = append(buf, rateLogMagic...)
+	}
+	for _, exchange := range rows {
+		key, err := encodeRateLogKey(exchange)
+		if err != nil {
+			return result, err
+		}
+		if rate, ok := stored[key]; ok {
+			result.Duplicates++
+			if rate != exchange.Rate {
+				result.Conflicts = append(result.Conflicts, exchange)
+			}
+			continue
+		}
+		stored[key] = exchange.Rate
+		buf = appendRateLogRecord(buf, key, exchange.Rate)
+		result.Appended++
+	}
+
+	if err := file.Truncate(end); err != nil {
+		return result, err
+	}
+	if _, err := file.WriteAt(buf, end); err != nil {
+		return result, err
+	}
+	return result, file.Sync()
+}
+
+// readRateLogKeys returns the rates stored in the log by key and the offset just past
+// its last complete record, which is 0 for an empty file.
+func readRateLogKeys(file *os.File) (map[rateLogKey]float64, int64, error) {
+	stored := make(map[rateLogKey]float64)
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	if info.Size() == 0 {
+		return stored, 0, nil
+	}
+	source, err := rateLogImporter{}.NewSource(file)
+	if err != nil {
+		return nil, 0, err
+	}
+	end := int64(len(rateLogMagic))
+	for {
+		exchange, err := source.(*rateLogSource).NextExchange()
+		var rowErr *MalformedRowError
+		if errors.As(err, &rowErr) && rowErr.Reason != reasonTruncatedRecord {
+			// A complete record with an unusable rate is kept; only a torn tail is overwritten.
+			end += rateLogRecordSize
+			continue
+		}
+		if err == io.EOF || rowErr != nil {
+			return stored, end, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		key, _ := encodeRateLogKey(exchange)
+		stored[key] = exchange.Rate
+		end += rateLogRecordSize
+	}
+}
+
+// defaultDataFile is the rate log when one has been imported, and the CSV file otherwise.
+func defaultDataFile() string {
+	if _, err := os.Stat(DefaultStoreFile); err == nil {
+		return DefaultStoreFile
+	}
+	return DefaultDataFile
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	source := addDataFlagsWithDefault(fs, DefaultDataFile)
+	store := fs.String("store", DefaultStoreFile, "Path of the rate log to create or append to")
+	fs.Parse(args)
+
+	data, err := source.load()
+	if err != nil {
+		return err
+	}
+	result, err := AppendToRateLog(*store, data)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Appended %d rows to %s, skipped %d duplicates (%d with a different rate)\n",
+		result.Appended, *store, result.Duplicates, len(result.Conflicts))
+	if *source.report {
+		for _, exchange := range result.Conflicts {
+			fmt.Fprintf(os.Stderr, "  conflict: %s %s %v\n", exchange.Period, exchange.Currency, exchange.Rate)
+		}
+	}
+	return nil
+}
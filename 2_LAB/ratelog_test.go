@@ -0,0 +1,130 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readTestRows(t *testing.T, csv string) []Exchange {
+	t.Helper()
+	data, _, err := ReadExchangeRates(strings.NewReader("Period;Currency;Rate\n"+csv), LoadOptions{Policy: FailOnInvalidRow})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func readRateLog(t *testing.T, path string) []string {
+	t.Helper()
+	data, _, err := LoadExchangeRatesWithOptions(path, LoadOptions{Policy: FailOnInvalidRow})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := make([]string, len(data))
+	for i, exchange := range data {
+		rows[i] = exchange.Period + " " + exchange.Currency + " " + exchange.Exact.String()
+	}
+	return rows
+}
+
+func TestAppendToRateLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.bin")
+	rows := readTestRows(t, "2020-01-02;USD;1.1193\n2020-01-02;JPY;121.75\n2020-01-03;USD;1.1147\n")
+
+	result, err := AppendToRateLog(path, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Appended != 3 || result.Duplicates != 0 {
+		t.Errorf("first import: got %+v, want 3 appended", result)
+	}
+
+	result, err = AppendToRateLog(path, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Appended != 0 || result.Duplicates != 3 || len(result.Conflicts) != 0 {
+		t.Errorf("second import: got %+v, want 3 duplicates", result)
+	}
+
+	changed := readTestRows(t, "2020-01-03;USD;1.1150\n")
+	result, err = AppendToRateLog(path, changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Appended != 0 || result.Duplicates != 1 || len(result.Conflicts) != 1 || result.Conflicts[0].Rate != 1.1150 {
+		t.Errorf("changed rate: got %+v, want one conflict", result)
+	}
+
+	want := []string{"2020-01-02 USD 1.1193", "2020-01-02 JPY 121.75", "2020-01-03 USD 1.1147"}
+	if got := readRateLog(t, path); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got rows %v, want %v", got, want)
+	}
+}
+
+func TestAppendToRateLogAfterTruncatedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.bin")
+	rows := readTestRows(t, "2020-01-02;USD;1.1193\n2020-01-02;JPY;121.75\n")
+	if _, err := AppendToRateLog(path, rows); err != nil {
+		t.Fatal(err)
+	}
+	// An interrupted append leaves part of the last record behind.
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, info.Size()-rateLogRecordSize/2); err != nil {
+		t.Fatal(err)
+	}
+
+	rows = append(rows, readTestRows(t, "2020-01-03;USD;1.1147\n")...)
+	result, err := AppendToRateLog(path, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Appended != 2 || result.Duplicates != 1 {
+		t.Errorf("got %+v, want the torn JPY row and the new row appended", result)
+	}
+
+	want := []string{"2020-01-02 USD 1.1193", "2020-01-02 JPY 121.75", "2020-01-03 USD 1.1147"}
+	if got := readRateLog(t, path); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got rows %v, want %v", got, want)
+	}
+}
+
+func TestAppendToRateLogKeepsInvalidRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.bin")
+	rows := readTestRows(t, "2020-01-02;USD;1.1193\n2020-01-03;USD;1.1147\n")
+
+	// A complete record with a NaN rate between two valid ones.
+	records := []Exchange{rows[0], {Date: rows[0].Date, Currency: "JPY", Rate: math.NaN()}, rows[1]}
+	buf := []byte(rateLogMagic)
+	for _, exchange := range records {
+		key, err := encodeRateLogKey(exchange)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf = appendRateLogRecord(buf, key, exchange.Rate)
+	}
+	if err := os.WriteFile(path, buf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := AppendToRateLog(path, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Appended != 0 || result.Duplicates != 2 {
+		t.Errorf("got %+v, want the records after the invalid one to be kept", result)
+	}
+	data, report, err := LoadExchangeRatesWithOptions(path, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2 || report.Reasons["invalid rate"] != 1 {
+		t.Errorf("got %d rows and %v, want 2 rows and one invalid rate", len(data), report.Reasons)
+	}
+}
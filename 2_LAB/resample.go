@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+type Granularity int
+
+const (
+	Weekly Granularity = iota
+	Monthly
+	Quarterly
+	Yearly
+)
+
+func (g Granularity) String() string {
+	switch g {
+	case Weekly:
+		return "weekly"
+	case Monthly:
+		return "monthly"
+	case Quarterly:
+		return "quarterly"
+	case Yearly:
+		return "yearly"
+	}
+	return fmt.Sprintf("Granularity(%d)", int(g))
+}
+
+func ParseGranularity(s string) (Granularity, error) {
+	for _, g := range []Granularity{Weekly, Monthly, Quarterly, Yearly} {
+		if g.String() == s {
+			return g, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown granularity %q (expected weekly, monthly, quarterly or yearly)", s)
+}
+
+// bucketStart returns the first day of the bucket the date falls into. Weeks start on Monday.
+func (g Granularity) bucketStart(date time.Time) time.Time {
+	year, month, day := date.Date()
+	switch g {
+	case Weekly:
+		offset := (int(date.Weekday()) + 6) % 7
+		return time.Date(year, month, day-offset, 0, 0, 0, 0, time.UTC)
+	case Monthly:
+		return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	case Quarterly:
+		return time.Date(year, month-(month-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+func (g Granularity) bucketEnd(start time.Time) time.Time {
+	switch g {
+	case Weekly:
+		return start.AddDate(0, 0, 6)
+	case Monthly:
+		return start.AddDate(0, 1, -1)
+	case Quarterly:
+		return start.AddDate(0, 3, -1)
+	default:
+		return start.AddDate(1, 0, -1)
+	}
+}
+
+type Bar struct {
+	Currency string    `json:"currency"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Open     Decimal   `json:"open"`
+	High     Decimal   `json:"high"`
+	Low      Decimal   `json:"low"`
+	Close    Decimal   `json:"close"`
+	Mean     Decimal   `json:"mean"`
+	Count    int       `json:"count"`
+}
+
+func Resample(data []Exchange, currency string, granularity Granularity) []Bar {
+	bars := NewBarAccumulator(currency, granularity)
+	for _, exchange := range data {
+		bars.Add(exchange)
+	}
+	return bars.Bars()
+}
+
+func WriteBarsCSV(w io.Writer, bars []Bar) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = ';'
+	if err := writer.Write([]string{"Start", "End", "Currency", "Open", "High", "Low", "Close", "Mean", "Count"}); err != nil {
+		return err
+	}
+	for _, bar := range bars {
+		err := writer.Write([]string{
+			bar.Start.Format(PeriodLayout),
+			bar.End.Format(PeriodLayout),
+			bar.Currency,
+			bar.Open.String(),
+			bar.High.String(),
+			bar.Low.String(),
+			bar.Close.String(),
+			bar.Mean.String(),
+			strconv.Itoa(bar.Count),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func WriteBarsJSON(w io.Writer, bars []Bar) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(bars)
+}
+
+func runResample(args []string) error {
+	fs := flag.NewFlagSet("resample", flag.ExitOnError)
+	source := addDataFlags(fs)
+	currency := fs.String("currency", "USD", "Currency to resample")
+	dates := addRangeFlags(fs)
+	interval := fs.String("interval", "monthly", "Bar interval: weekly, monthly, quarterly or yearly")
+	format := fs.String("format", "csv", "Output format: csv or json")
+	output := fs.String("out", "", "Output file (stdout when empty)")
+	fs.Parse(args)
+
+	granularity, err := ParseGranularity(*interval)
+	if err != nil {
+		return err
+	}
+	query, err := dates.query(*currency)
+	if err != nil {
+		return err
+	}
+
+	accumulator := NewBarAccumulator(*currency, granularity)
+	if err := source.stream(query, accumulator.Add); err != nil {
+		return err
+	}
+	bars := accumulator.Bars()
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch *format {
+	case "csv":
+		return WriteBarsCSV(w, bars)
+	case "json":
+		return WriteBarsJSON(w, bars)
+	}
+	return fmt.Errorf("unknown format %q (expected csv or json)", *format)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+type Server struct {
+	store *RateStore
+}
+
+func NewServer(store *RateStore) *Server {
+	return &Server{store: store}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rates/{currency}", s.handleRates)
+	mux.HandleFunc("GET /convert", s.handleConvert)
+	mux.HandleFunc("GET /stats/{currency}", s.handleStats)
+	mux.HandleFunc("GET /currencies", s.handleCurrencies)
+	return mux
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type badRequestError struct {
+	message string
+}
+
+func (e *badRequestError) Error() string {
+	return e.message
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var badRequest *badRequestError
+	switch {
+	case errors.As(err, &badRequest):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrUnknownCurrency), errors.Is(err, ErrRateNotFound):
+		status = http.StatusNotFound
+	}
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func dateParam(r *http.Request, name string) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := ParsePeriod(value)
+	if err != nil {
+		return time.Time{}, &badRequestError{message: fmt.Sprintf("parameter %s: %v", name, err)}
+	}
+	return date, nil
+}
+
+func dateRangeParams(r *http.Request) (time.Time, time.Time, error) {
+	from, err := dateParam(r, "from")
+	if err != nil {
+		return from, time.Time{}, err
+	}
+	to, err := dateParam(r, "to")
+	if err != nil {
+		return from, to, err
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return from, to, &badRequestError{message: "parameter to is before from"}
+	}
+	return from, to, nil
+}
+
+func roundingParam(r *http.Request) (RoundingMode, error) {
+	value := r.URL.Query().Get("rounding")
+	if value == "" {
+		return RoundHalfEven, nil
+	}
+	mode, err := ParseRoundingMode(value)
+	if err != nil {
+		return mode, &badRequestError{message: fmt.Sprintf("parameter rounding: %v", err)}
+	}
+	return mode, nil
+}
+
+func (s *Server) handleRates(w http.ResponseWriter, r *http.Request) {
+	from, to, err := dateRangeParams(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	rates, err := s.store.Range(r.PathValue("currency"), from, to)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if rates == nil {
+		rates = []Exchange{}
+	}
+	writeJSON(w, http.StatusOK, rates)
+}
+
+func (s *Server) handleConvert(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	fromCurrency, toCurrency := query.Get("from"), query.Get("to")
+	if fromCurrency == "" || toCurrency == "" {
+		writeError(w, &badRequestError{message: "parameters from and to are required"})
+		return
+	}
+	amount := Money{Amount: NewDecimal(1, 0), Currency: fromCurrency}
+	if value := query.Get("amount"); value != "" {
+		var err error
+		amount, err = ParseMoney(value, fromCurrency)
+		if err != nil {
+			writeError(w, &badRequestError{message: fmt.Sprintf("parameter amount: invalid number %q", value)})
+			return
+		}
+	}
+	mode, err := roundingParam(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	date, err := dateParam(r, "date")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if date.IsZero() {
+		date = s.store.LastDate()
+	}
+
+	converted, rate, err := s.store.Convert(amount, toCurrency, date, mode)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newConversionResult(amount, converted, rate, mode))
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	from, to, err := dateRangeParams(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	currency := r.PathValue("currency")
+	rates, err := s.store.Range(currency, from, to)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	mode, err := roundingParam(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	stats := NewStatsAccumulator(currency)
+	for _, exchange := range rates {
+		stats.Add(exchange)
+	}
+	result, err := newStatsResult(stats, mode)
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: %s in the requested range", ErrRateNotFound, currency))
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleCurrencies(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.store.Currencies())
+}
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	source := addDataFlags(fs)
+	addr := fs.String("addr", ":8080", "Address to listen on")
+	fs.Parse(args)
+
+	data, err := source.load()
+	if err != nil {
+		return err
+	}
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           NewServer(NewRateStore(data)).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("Serving %d exchange rates on %s", len(data), *addr)
+	return server.ListenAndServe()
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const serverTestData = `Period;Currency;Rate
+2019-01-02;USD;1.1397
+2019-01-02;GBP;0.9
+2019-01-03;USD;1.1348
+2019-01-03;GBP;0.8987
+2019-01-04;USD;1.1403
+2019-01-04;GBP;
+2019-01-07;USD;1.1445
+2019-01-07;GBP;0.8955
+2019-01-08;GBP;0.905
+`
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	data, _, err := ReadExchangeRates(strings.NewReader(serverTestData), LoadOptions{})
+	if err != nil {
+		t.Fatalf("ReadExchangeRates: %v", err)
+	}
+	server := httptest.NewServer(NewServer(NewRateStore(data)).Handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+func getJSON(t *testing.T, server *httptest.Server, path string, wantStatus int, value any) {
+	t.Helper()
+	resp, err := http.Get(server.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("GET %s: status %d, want %d", path, resp.StatusCode, wantStatus)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("GET %s: Content-Type %q, want application/json", path, got)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(value); err != nil {
+		t.Fatalf("GET %s: decoding response: %v", path, err)
+	}
+}
+
+func TestServerRates(t *testing.T) {
+	server := newTestServer(t)
+
+	var rates []Exchange
+	getJSON(t, server, "/rates/USD?from=2019-01-03&to=2019-01-04", http.StatusOK, &rates)
+	if len(rates) != 2 || rates[0].Period != "2019-01-03" || rates[1].Period != "2019-01-04" {
+		t.Errorf("unexpected rates: %+v", rates)
+	}
+
+	getJSON(t, server, "/rates/USD?from=2020-01-01", http.StatusOK, &rates)
+	if len(rates) != 0 {
+		t.Errorf("expected no rates after the data, got %+v", rates)
+	}
+}
+
+func TestServerConvert(t *testing.T) {
+	server := newTestServer(t)
+
+	var result conversionResult
+	getJSON(t, server, "/convert?amount=100&from=USD&to=GBP&date=2019-01-03", http.StatusOK, &result)
+	if result.Result.String() != "79.19" || result.Period != "2019-01-03" {
+		t.Errorf("got %+v, want 79.19 on 2019-01-03", result)
+	}
+
+	// 1 EUR is exactly 0.905 GBP on 2019-01-08, so the rounding mode decides the tie.
+	for rounding, want := range map[string]string{"half-even": "0.90", "half-up": "0.91"} {
+		getJSON(t, server, "/convert?from=EUR&to=GBP&date=2019-01-08&rounding="+rounding, http.StatusOK, &result)
+		if result.Result.String() != want {
+			t.Errorf("%s: got %s, want %s", rounding, result.Result, want)
+		}
+	}
+
+	// GBP has no rate on 2019-01-04, so the previous day is used for that leg.
+	getJSON(t, server, "/convert?amount=1&from=EUR&to=GBP&date=2019-01-04", http.StatusOK, &result)
+	if result.Rate.Cmp(NewDecimal(8987, 4)) != 0 {
+		t.Errorf("got rate %v, want the 2019-01-03 GBP rate", result.Rate)
+	}
+}
+
+func TestServerStats(t *testing.T) {
+	server := newTestServer(t)
+
+	var stats statsResult
+	getJSON(t, server, "/stats/USD", http.StatusOK, &stats)
+	if stats.Highest.Period != "2019-01-07" || stats.Lowest.Period != "2019-01-03" {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	server := newTestServer(t)
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/rates/XXX", http.StatusNotFound},
+		{"/rates/USD?from=2019-13-01", http.StatusBadRequest},
+		{"/rates/USD?from=2019-01-07&to=2019-01-02", http.StatusBadRequest},
+		{"/stats/XXX", http.StatusNotFound},
+		{"/stats/USD?from=2020-01-01", http.StatusNotFound},
+		{"/convert?amount=abc&from=USD&to=GBP", http.StatusBadRequest},
+		{"/convert?amount=1&from=USD", http.StatusBadRequest},
+		{"/convert?amount=1&from=USD&to=GBP&rounding=down", http.StatusBadRequest},
+		{"/convert?amount=1&from=USD&to=XXX", http.StatusNotFound},
+		{"/convert?amount=1&from=USD&to=GBP&date=2018-12-31", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		var resp errorResponse
+		getJSON(t, server, tt.path, tt.status, &resp)
+		if resp.Error == "" {
+			t.Errorf("GET %s: empty error message", tt.path)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+var ErrRateNotFound = errors.New("rate not found")
+
+// RateStore indexes exchange rates by currency and day. It is read-only after
+// construction, so it is safe for concurrent use.
+type RateStore struct {
+	series map[string]*currencyIndex
+	first  time.Time
+	last   time.Time
+}
+
+// currencyIndex keeps the rates of one currency ordered by date together with a dense
+// per-day index: onOrBefore[d] is the position in rates of the last observation on or
+// before first+d days, or -1 when there is none.
+type currencyIndex struct {
+	rates      []Exchange
+	first      time.Time
+	onOrBefore []int32
+}
+
+func NewRateStore(data []Exchange) *RateStore {
+	byCurrency := make(map[string][]Exchange)
+	for _, exchange := range data {
+		byCurrency[exchange.Currency] = append(byCurrency[exchange.Currency], exchange)
+	}
+
+	store := &RateStore{series: make(map[string]*currencyIndex, len(byCurrency))}
+	for currency, rates := range byCurrency {
+		sort.SliceStable(rates, func(i, j int) bool {
+			return rates[i].Date.Before(rates[j].Date)
+		})
+		rates = dedupeByDate(rates)
+		index := &currencyIndex{
+			rates: rates,
+			first: rates[0].Date,
+		}
+		days := dayNumber(rates[len(rates)-1].Date, index.first) + 1
+		index.onOrBefore = make([]int32, days)
+		pos := int32(-1)
+		for day := range index.onOrBefore {
+			for int(pos)+1 < len(rates) && dayNumber(rates[pos+1].Date, index.first) <= day {
+				pos++
+			}
+			index.onOrBefore[day] = pos
+		}
+		store.series[currency] = index
+
+		if store.first.IsZero() || index.first.Before(store.first) {
+			store.first = index.first
+		}
+		if last := rates[len(rates)-1].Date; last.After(store.last) {
+			store.last = last
+		}
+	}
+	return store
+}
+
+// dedupeByDate keeps the last row of every date, matching how later rows of the file
+// overwrite earlier ones.
+func dedupeByDate(rates []Exchange) []Exchange {
+	result := rates[:0]
+	for _, exchange := range rates {
+		if n := len(result); n > 0 && result[n-1].Date.Equal(exchange.Date) {
+			result[n-1] = exchange
+			continue
+		}
+		result = append(result, exchange)
+	}
+	return result
+}
+
+func dayNumber(date, first time.Time) int {
+	return int(date.Sub(first).Hours() / 24)
+}
+
+func (s *RateStore) index(currency string) (*currencyIndex, error) {
+	index, ok := s.series[currency]
+	if !ok {
+		return nil, &UnknownCurrencyError{Currency: currency}
+	}
+	return index, nil
+}
+
+func (s *RateStore) Currencies() []string {
+	currencies := make([]string, 0, len(s.series))
+	for currency := range s.series {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+func (s *RateStore) FirstDate() time.Time {
+	return s.first
+}
+
+func (s *RateStore) LastDate() time.Time {
+	return s.last
+}
+
+func (index *currencyIndex) positionOnOrBefore(date time.Time) int {
+	day := dayNumber(date, index.first)
+	if date.Before(index.first) {
+		return -1
+	}
+	if day >= len(index.onOrBefore) {
+		return len(index.rates) - 1
+	}
+	return int(index.onOrBefore[day])
+}
+
+// Rate returns the rate published exactly on the given day.
+func (s *RateStore) Rate(currency string, date time.Time) (Exchange, error) {
+	exchange, err := s.RateOnOrBefore(currency, date)
+	if err != nil {
+		return Exchange{}, err
+	}
+	if !exchange.Date.Equal(date) {
+		return Exchange{}, fmt.Errorf("%w: %s on %s", ErrRateNotFound, currency, date.Format(PeriodLayout))
+	}
+	return exchange, nil
+}
+
+// RateOnOrBefore returns the most recent rate published on or before the given day.
+func (s *RateStore) RateOnOrBefore(currency string, date time.Time) (Exchange, error) {
+	index, err := s.index(currency)
+	if err != nil {
+		return Exchange{}, err
+	}
+	pos := index.positionOnOrBefore(date)
+	if pos < 0 {
+		return Exchange{}, fmt.Errorf("%w: %s on or before %s", ErrRateNotFound, currency, date.Format(PeriodLayout))
+	}
+	return index.rates[pos], nil
+}
+
+// Range returns the rates of the currency between from and to inclusive, ordered by
+// date. A zero from or to leaves that side open. The result shares memory with the
+// store and must not be modified.
+func (s *RateStore) Range(currency string, from, to time.Time) ([]Exchange, error) {
+	index, err := s.index(currency)
+	if err != nil {
+		return nil, err
+	}
+	start := 0
+	if !from.IsZero() {
+		start = index.positionOnOrBefore(from.AddDate(0, 0, -1)) + 1
+	}
+	end := len(index.rates)
+	if !to.IsZero() {
+		end = index.positionOnOrBefore(to) + 1
+	}
+	if start >= end {
+		return nil, nil
+	}
+	return slices.Clip(index.rates[start:end]), nil
+}
+
+// legs returns the EUR legs of both currencies on or before the day.
+func (s *RateStore) legs(base, quote string, date time.Time) (Exchange, Exchange, error) {
+	leg := func(currency string) (Exchange, error) {
+		if currency == BaseCurrency {
+			return Exchange{Currency: BaseCurrency, Rate: 1, Exact: NewDecimal(1, 0)}, nil
+		}
+		return s.RateOnOrBefore(currency, date)
+	}
+	baseLeg, err := leg(base)
+	if err != nil {
+		return Exchange{}, Exchange{}, err
+	}
+	quoteLeg, err := leg(quote)
+	if err != nil {
+		return Exchange{}, Exchange{}, err
+	}
+	return baseLeg, quoteLeg, nil
+}
+
+// CrossRate returns the base/quote rate using the latest legs on or before the day.
+// The Period of the result is the later of the two legs' periods.
+func (s *RateStore) CrossRate(base, quote string, date time.Time) (CrossRate, error) {
+	baseLeg, quoteLeg, err := s.legs(base, quote, date)
+	if err != nil {
+		return CrossRate{}, err
+	}
+	rate, err := quoteLeg.Exact.Quo(baseLeg.Exact, RateScale, RoundHalfEven)
+	if err != nil {
+		return CrossRate{}, err
+	}
+	return CrossRate{Period: max(baseLeg.Period, quoteLeg.Period), Base: base, Quote: quote, Rate: rate}, nil
+}
+
+// Convert converts amount into the target currency using exact decimal legs on or
+// before the day. The result is rounded once, to the minor units of the target currency.
+func (s *RateStore) Convert(amount Money, to string, date time.Time, mode RoundingMode) (Money, CrossRate, error) {
+	baseLeg, quoteLeg, err := s.legs(amount.Currency, to, date)
+	if err != nil {
+		return Money{}, CrossRate{}, err
+	}
+	converted, err := amount.Amount.Mul(quoteLeg.Exact).Quo(baseLeg.Exact, MinorUnits(to), mode)
+	if err != nil {
+		return Money{}, CrossRate{}, err
+	}
+	rate, err := quoteLeg.Exact.Quo(baseLeg.Exact, RateScale, mode)
+	if err != nil {
+		return Money{}, CrossRate{}, err
+	}
+	crossRate := CrossRate{Period: max(baseLeg.Period, quoteLeg.Period), Base: amount.Currency, Quote: to, Rate: rate}
+	return Money{Amount: converted, Currency: to}, crossRate, nil
+}
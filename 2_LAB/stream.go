@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// ExchangeReader decodes exchange rates one row at a time, so that files of any size
+// can be processed in constant memory.
+type ExchangeReader struct {
+	source RowSource
+	opts   LoadOptions
+	report *LoadReport
+	closer io.Closer
+	err    error
+}
+
+func NewExchangeReader(r io.Reader, opts LoadOptions) (*ExchangeReader, error) {
+	source, err := NewRowSource(r, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+	return &ExchangeReader{
+		source: source,
+		opts:   opts,
+		report: &LoadReport{Reasons: make(map[string]int)},
+	}, nil
+}
+
+func OpenExchangeReader(filepath string, opts LoadOptions) (*ExchangeReader, error) {
+	file, err := openDataFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewExchangeReader(file, opts)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", filepath, err)
+	}
+	r.closer = file
+	return r, nil
+}
+
+func openDataFile(filepath string) (*os.File, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &FileNotFoundError{Path: filepath, Err: err}
+		}
+		return nil, fmt.Errorf("could not read file %s: %w", filepath, err)
+	}
+	return file, nil
+}
+
+// Next returns the next valid row, applying the row policy to malformed ones.
+// It returns io.EOF once the input is exhausted.
+func (r *ExchangeReader) Next() (Exchange, error) {
+	if r.err != nil {
+		return Exchange{}, r.err
+	}
+	if source, ok := r.source.(exchangeSource); ok {
+		return r.nextExchange(source)
+	}
+	for {
+		row, err := r.source.Next()
+		if err == io.EOF {
+			r.err = io.EOF
+			return Exchange{}, io.EOF
+		}
+		var rowErr *MalformedRowError
+		if err != nil && !errors.As(err, &rowErr) {
+			r.err = err
+			return Exchange{}, err
+		}
+		var exchange Exchange
+		if rowErr == nil {
+			exchange, rowErr = parseRawRow(row)
+		}
+		if rowErr != nil {
+			if r.opts.Policy == FailOnInvalidRow && rowErr.Reason != ReasonMissingRate {
+				r.err = rowErr
+				return Exchange{}, rowErr
+			}
+			r.report.skip(rowErr)
+			continue
+		}
+		r.report.Loaded++
+		return exchange, nil
+	}
+}
+
+// exchangeSource is implemented by row sources that decode typed rows directly, which
+// saves formatting and parsing them as text.
+type exchangeSource interface {
+	NextExchange() (Exchange, error)
+}
+
+func (r *ExchangeReader) nextExchange(source exchangeSource) (Exchange, error) {
+	for {
+		exchange, err := source.NextExchange()
+		var rowErr *MalformedRowError
+		if errors.As(err, &rowErr) && (r.opts.Policy != FailOnInvalidRow || rowErr.Reason == ReasonMissingRate) {
+			r.report.skip(rowErr)
+			continue
+		}
+		if err != nil {
+			r.err = err
+			return Exchange{}, err
+		}
+		r.report.Loaded++
+		return exchange, nil
+	}
+}
+
+// All yields every valid row. Iteration stops after the first error, which is
+// yielded together with a zero Exchange.
+func (r *ExchangeReader) All() iter.Seq2[Exchange, error] {
+	return func(yield func(Exchange, error) bool) {
+		for {
+			exchange, err := r.Next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(exchange, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Channel streams the rows through a channel. The error channel receives at most one
+// value and is closed after the exchange channel. Cancelling ctx stops the goroutine
+// and sends ctx.Err(), so a consumer that stops reading early does not leak it; the
+// reader still has to be closed.
+func (r *ExchangeReader) Channel(ctx context.Context, buffer int) (<-chan Exchange, <-chan error) {
+	exchanges := make(chan Exchange, buffer)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(exchanges)
+		for exchange, err := range r.All() {
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case exchanges <- exchange:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+	}()
+	return exchanges, errs
+}
+
+func (r *ExchangeReader) Report() *LoadReport {
+	return r.report
+}
+
+func (r *ExchangeReader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+func FilterSeq(seq iter.Seq2[Exchange, error], query ExchangeQuery) iter.Seq2[Exchange, error] {
+	return func(yield func(Exchange, error) bool) {
+		for exchange, err := range seq {
+			if err != nil {
+				yield(exchange, err)
+				return
+			}
+			if query.Matches(exchange) && !yield(exchange, nil) {
+				return
+			}
+		}
+	}
+}
+
+type StatsAccumulator struct {
+	Currency string
+	count    int
+	sum      Decimal
+	highest  Exchange
+	lowest   Exchange
+}
+
+func NewStatsAccumulator(currency string) *StatsAccumulator {
+	return &StatsAccumulator{
+		Currency: currency,
+		highest:  Exchange{Rate: -math.MaxFloat64},
+		lowest:   Exchange{Rate: math.MaxFloat64},
+	}
+}
+
+func (a *StatsAccumulator) Add(exchange Exchange) {
+	if exchange.Currency != a.Currency {
+		return
+	}
+	a.count++
+	a.sum = a.sum.Add(exchange.Exact)
+	if exchange.Rate > a.highest.Rate {
+		a.highest = exchange
+	}
+	if exchange.Rate < a.lowest.Rate {
+		a.lowest = exchange
+	}
+}
+
+// Result returns the average, which is ExactAverage rounded half-even to RateScale
+// places, and the rows with the highest and lowest rate.
+func (a *StatsAccumulator) Result() (float64, Exchange, Exchange, error) {
+	average, err := a.ExactAverage(RateScale, RoundHalfEven)
+	if err != nil {
+		return 0, Exchange{}, Exchange{}, err
+	}
+	return average.Float64(), a.highest, a.lowest, nil
+}
+
+// ExactAverage returns the average computed from the exact decimal rates, rounded to
+// scale decimal places.
+func (a *StatsAccumulator) ExactAverage(scale int32, mode RoundingMode) (Decimal, error) {
+	if a.count == 0 {
+		return Decimal{}, &UnknownCurrencyError{Currency: a.Currency}
+	}
+	return a.sum.Quo(NewDecimal(int64(a.count), 0), scale, mode)
+}
+
+type barState struct {
+	bar       Bar
+	sum       Decimal
+	openDate  time.Time
+	closeDate time.Time
+}
+
+// BarAccumulator builds OHLC bars from rows in any order. Memory grows with the number
+// of bars, not with the number of rows.
+type BarAccumulator struct {
+	Currency    string
+	Granularity Granularity
+	bars        map[time.Time]*barState
+}
+
+func NewBarAccumulator(currency string, granularity Granularity) *BarAccumulator {
+	return &BarAccumulator{
+		Currency:    currency,
+		Granularity: granularity,
+		bars:        make(map[time.Time]*barState),
+	}
+}
+
+func (a *BarAccumulator) Add(exchange Exchange) {
+	if exchange.Currency != a.Currency {
+		return
+	}
+	start := a.Granularity.bucketStart(exchange.Date)
+	state, ok := a.bars[start]
+	if !ok {
+		state = &barState{
+			bar: Bar{
+				Currency: a.Currency,
+				Start:    start,
+				End:      a.Granularity.bucketEnd(start),
+				Open:     exchange.Exact,
+				High:     exchange.Exact,
+				Low:      exchange.Exact,
+				Close:    exchange.Exact,
+			},
+			openDate:  exchange.Date,
+			closeDate: exchange.Date,
+		}
+		a.bars[start] = state
+	}
+	bar := &state.bar
+	if exchange.Exact.Cmp(bar.High) > 0 {
+		bar.High = exchange.Exact
+	}
+	if exchange.Exact.Cmp(bar.Low) < 0 {
+		bar.Low = exchange.Exact
+	}
+	if exchange.Date.Before(state.openDate) {
+		bar.Open = exchange.Exact
+		state.openDate = exchange.Date
+	}
+	if !exchange.Date.Before(state.closeDate) {
+		bar.Close = exchange.Exact
+		state.closeDate = exchange.Date
+	}
+	bar.Count++
+	state.sum = state.sum.Add(exchange.Exact)
+}
+
+func (a *BarAccumulator) Bars() []Bar {
+	bars := make([]Bar, 0, len(a.bars))
+	for _, state := range a.bars {
+		bar := state.bar
+		// Every bar holds at least one row, so the division cannot fail.
+		bar.Mean, _ = state.sum.Quo(NewDecimal(int64(bar.Count), 0), RateScale, RoundHalfEven)
+		bars = append(bars, bar)
+	}
+	sort.Slice(bars, func(i, j int) bool {
+		return bars[i].Start.Before(bars[j].Start)
+	})
+	return bars
+}
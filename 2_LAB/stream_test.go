@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const benchmarkFile = "./euro-exchange-rates.csv"
+
+func TestParseRawRowRejectsUnusableRates(t *testing.T) {
+	for rate, want := range map[string]error{
+		"NaN":  ErrNonFiniteRate,
+		"Inf":  ErrNonFiniteRate,
+		"-Inf": ErrNonFiniteRate,
+		"0":    ErrNonPositiveRate,
+		"-1.5": ErrNonPositiveRate,
+	} {
+		_, rowErr := parseRawRow(RawRow{Line: 2, Period: "2020-01-02", Currency: "USD", Rate: rate})
+		if rowErr == nil || !errors.Is(rowErr, want) {
+			t.Errorf("rate %q: got %v, want %v", rate, rowErr, want)
+		}
+	}
+}
+
+func TestExchangeReaderChannelCancel(t *testing.T) {
+	input := "Period;Currency;Rate\n" + strings.Repeat("2020-01-02;USD;1.1\n", 100)
+	reader, err := NewExchangeReader(strings.NewReader(input), LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	exchanges, errs := reader.Channel(ctx, 0)
+	<-exchanges
+	cancel()
+	for range exchanges {
+	}
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("after cancel: got %v, want context.Canceled", err)
+	}
+}
+
+func TestExchangeReaderRateLogPolicy(t *testing.T) {
+	key, err := encodeRateLogKey(Exchange{Date: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), Currency: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := appendRateLogRecord([]byte(rateLogMagic), key, 1.1)
+	input = append(input, 1, 2, 3)
+
+	for _, policy := range []RowPolicy{SkipInvalidRows, FailOnInvalidRow} {
+		reader, err := NewExchangeReader(bytes.NewReader(input), LoadOptions{Policy: policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var rows int
+		for _, err = range reader.All() {
+			if err != nil {
+				break
+			}
+			rows++
+		}
+		if rows != 1 {
+			t.Errorf("policy %d: read %d rows, want 1", policy, rows)
+		}
+		if failed := errors.Is(err, ErrMalformedRow); failed != (policy == FailOnInvalidRow) {
+			t.Errorf("policy %d: truncated record gave %v", policy, err)
+		}
+	}
+}
+
+func BenchmarkLoadExchangeRatesStats(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		data, err := LoadExchangeRates(benchmarkFile)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, _, _, err := GetCurrencyStats(data, "USD"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExchangeReaderStats(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		reader, err := OpenExchangeReader(benchmarkFile, LoadOptions{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		stats := NewStatsAccumulator("USD")
+		for exchange, err := range reader.All() {
+			if err != nil {
+				b.Fatal(err)
+			}
+			stats.Add(exchange)
+		}
+		reader.Close()
+		if _, _, _, err := stats.Result(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExchangeReaderChannelStats(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		reader, err := OpenExchangeReader(benchmarkFile, LoadOptions{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		stats := NewStatsAccumulator("USD")
+		exchanges, errs := reader.Channel(context.Background(), 64)
+		for exchange := range exchanges {
+			stats.Add(exchange)
+		}
+		if err := <-errs; err != nil {
+			b.Fatal(err)
+		}
+		reader.Close()
+		if _, _, _, err := stats.Result(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoadExchangeRatesResample(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		data, err := LoadExchangeRates(benchmarkFile)
+		if err != nil {
+			b.Fatal(err)
+		}
+		Resample(data, "USD", Monthly)
+	}
+}
+
+func BenchmarkExchangeReaderResample(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		reader, err := OpenExchangeReader(benchmarkFile, LoadOptions{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		bars := NewBarAccumulator("USD", Monthly)
+		for exchange, err := range reader.All() {
+			if err != nil {
+				b.Fatal(err)
+			}
+			bars.Add(exchange)
+		}
+		reader.Close()
+		bars.Bars()
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+type fileContent interface {
+	FileSystemItem
+	contentBytes() []byte
+}
+
+type writableContent interface {
+	fileContent
+	setContent(content []byte)
+}
+
+// FileHandle is an open file with its own offset, so several handles to the same file
+// can read and write independently. Flags are the os.O_* constants.
+type FileHandle struct {
+	file   fileContent
+	flag   int
+	offset int64
+	closed bool
+}
+
+func accessMode(flag int) int {
+	return flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR)
+}
+
+// accessFor returns the permission bits needed to open a file with flag.
+func accessFor(flag int) os.FileMode {
+	switch accessMode(flag) {
+	case os.O_WRONLY:
+		return accessWrite
+	case os.O_RDWR:
+		return accessRead | accessWrite
+	}
+	return accessRead
+}
+
+// OpenFile opens the file at path with the given os.O_* flags.
+func (vfs *VirtualFileSystem) OpenFile(path string, flag int) (*FileHandle, error) {
+	item, err := vfs.FindItem(path)
+	if err == ErrItemNotFound && flag&os.O_CREATE != 0 {
+		item, err = vfs.createForOpen(path)
+	} else if err == nil && flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+		return nil, ErrItemExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	if item, err = vfs.follow(item, new(int)); err != nil {
+		return nil, err
+	}
+	return vfs.openItem(item, flag)
+}
+
+// openItem opens an item that has already been looked up, checking that the user may
+// access it the way flag asks for.
+func (vfs *VirtualFileSystem) openItem(item FileSystemItem, flag int) (*FileHandle, error) {
+	var file fileContent
+	switch f := item.(type) {
+	case Directory:
+		return nil, ErrIsDirectory
+	case fileContent:
+		file = f
+	default:
+		return nil, ErrNotImplemented
+	}
+
+	writable := accessMode(flag) != os.O_RDONLY
+	if _, ok := file.(writableContent); writable && !ok {
+		return nil, ErrPermissionDenied
+	}
+	if err := vfs.checkAccess(file, accessFor(flag)); err != nil {
+		return nil, err
+	}
+
+	handle := &FileHandle{file: file, flag: flag}
+	if writable && flag&os.O_TRUNC != 0 {
+		handle.file.(writableContent).setContent(nil)
+	}
+	return handle, nil
+}
+
+func (vfs *VirtualFileSystem) createForOpen(path string) (FileSystemItem, error) {
+	parts := splitPath(path)
+	if len(parts) == 0 {
+		return nil, ErrIsDirectory
+	}
+	parentPath := "/" + strings.Join(parts[:len(parts)-1], "/")
+	dir, err := vfs.getOrCreateDirPath(parentPath, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := vfs.checkAccess(dir, accessWrite|accessExec); err != nil {
+		return nil, err
+	}
+
+	name := parts[len(parts)-1]
+	file := NewPlik(name, joinPath(dir.Path(), name))
+	vfs.own(file)
+	if err := dir.AddItem(file); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (h *FileHandle) Name() string {
+	return h.file.Name()
+}
+
+func (h *FileHandle) Stat() (fs.FileInfo, error) {
+	if h.closed {
+		return nil, ErrClosed
+	}
+	return ItemInfo{h.file}, nil
+}
+
+func (h *FileHandle) checkRead() error {
+	if h.closed {
+		return ErrClosed
+	}
+	if accessMode(h.flag) == os.O_WRONLY {
+		return ErrNotReadable
+	}
+	return nil
+}
+
+func (h *FileHandle) checkWrite() error {
+	if h.closed {
+		return ErrClosed
+	}
+	if accessMode(h.flag) == os.O_RDONLY {
+		return ErrNotWritable
+	}
+	return nil
+}
+
+func (h *FileHandle) Read(b []byte) (n int, err error) {
+	if err := h.checkRead(); err != nil {
+		return 0, err
+	}
+	n, err = readContent(h.file.contentBytes(), b, h.offset)
+	h.offset += int64(n)
+	return n, err
+}
+
+// ReadAt reads from off without moving the offset of the handle. Like io.ReaderAt, it
+// returns io.EOF whenever fewer than len(b) bytes are read.
+func (h *FileHandle) ReadAt(b []byte, off int64) (n int, err error) {
+	if err := h.checkRead(); err != nil {
+		return 0, err
+	}
+	if off < 0 {
+		return 0, ErrInvalidOffset
+	}
+	n, err = readContent(h.file.contentBytes(), b, off)
+	if err == nil && n < len(b) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Write writes at the offset of the handle, or at the end of the file when it was opened
+// with os.O_APPEND. Writing past the end fills the gap with zeros.
+func (h *FileHandle) Write(b []byte) (n int, err error) {
+	if err := h.checkWrite(); err != nil {
+		return 0, err
+	}
+	if h.flag&os.O_APPEND != 0 {
+		h.offset = int64(len(h.file.contentBytes()))
+	}
+	h.writeAt(b, h.offset)
+	h.offset += int64(len(b))
+	return len(b), nil
+}
+
+func (h *FileHandle) WriteAt(b []byte, off int64) (n int, err error) {
+	if err := h.checkWrite(); err != nil {
+		return 0, err
+	}
+	if h.flag&os.O_APPEND != 0 {
+		return 0, ErrAppendWriteAt
+	}
+	if off < 0 {
+		return 0, ErrInvalidOffset
+	}
+	h.writeAt(b, off)
+	return len(b), nil
+}
+
+func (h *FileHandle) writeAt(b []byte, off int64) {
+	file := h.file.(writableContent)
+	content := file.contentBytes()
+	if end := off + int64(len(b)); end > int64(len(content)) {
+		content = append(content, make([]byte, end-int64(len(content)))...)
+	}
+	copy(content[off:], b)
+	file.setContent(content)
+}
+
+func (h *FileHandle) Seek(offset int64, whence int) (int64, error) {
+	if h.closed {
+		return 0, ErrClosed
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += h.offset
+	case io.SeekEnd:
+		offset += int64(len(h.file.contentBytes()))
+	case io.SeekStart:
+	default:
+		return 0, ErrInvalidOffset
+	}
+	if offset < 0 {
+		return 0, ErrInvalidOffset
+	}
+	h.offset = offset
+	return offset, nil
+}
+
+// Truncate changes the size of the file, cutting it or extending it with zeros. The
+// offset of the handle is left unchanged.
+func (h *FileHandle) Truncate(size int64) error {
+	if err := h.checkWrite(); err != nil {
+		return err
+	}
+	if size < 0 {
+		return ErrInvalidOffset
+	}
+	file := h.file.(writableContent)
+	content := file.contentBytes()
+	if size <= int64(len(content)) {
+		file.setContent(content[:size:size])
+	} else {
+		file.setContent(append(content, make([]byte, size-int64(len(content)))...))
+	}
+	return nil
+}
+
+func (h *FileHandle) Close() error {
+	if h.closed {
+		return ErrClosed
+	}
+	h.closed = true
+	return nil
+}
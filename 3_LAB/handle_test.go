@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func newHandleTestFS(t *testing.T, content string) *VirtualFileSystem {
+	t.Helper()
+	vfs := NewVirtualFileSystem()
+	file, err := vfs.CreateFile("/home", "plik.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.(*Plik).Write([]byte(content))
+	return vfs
+}
+
+func openHandle(t *testing.T, vfs *VirtualFileSystem, flag int) *FileHandle {
+	t.Helper()
+	handle, err := vfs.OpenFile("/home/plik.txt", flag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return handle
+}
+
+func TestFileHandleReadAll(t *testing.T) {
+	vfs := newHandleTestFS(t, "pierwsza linia\n")
+	data, err := io.ReadAll(openHandle(t, vfs, os.O_RDONLY))
+	if err != nil || string(data) != "pierwsza linia\n" {
+		t.Errorf("ReadAll = %q, %v", data, err)
+	}
+}
+
+func TestFileHandleOffsets(t *testing.T) {
+	vfs := newHandleTestFS(t, "abcdef")
+	first := openHandle(t, vfs, os.O_RDONLY)
+	second := openHandle(t, vfs, os.O_RDONLY)
+	buf := make([]byte, 4)
+	if n, _ := first.Read(buf); string(buf[:n]) != "abcd" {
+		t.Errorf("first handle read %q", buf[:n])
+	}
+	if n, _ := second.Read(buf[:2]); string(buf[:n]) != "ab" {
+		t.Errorf("second handle read %q", buf[:n])
+	}
+	if n, _ := first.Read(buf); string(buf[:n]) != "ef" {
+		t.Errorf("first handle continued with %q", buf[:n])
+	}
+}
+
+func TestFileHandleWriteAfterSeek(t *testing.T) {
+	vfs := newHandleTestFS(t, "ab")
+	handle := openHandle(t, vfs, os.O_RDWR)
+	if _, err := handle.Seek(2, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	handle.Write([]byte("cd"))
+	data, _ := vfs.ReadFile("home/plik.txt")
+	if want := []byte("ab\x00\x00cd"); !bytes.Equal(data, want) {
+		t.Errorf("content %q, want %q", data, want)
+	}
+}
+
+func TestFileHandleReadAt(t *testing.T) {
+	vfs := newHandleTestFS(t, "abcdef")
+	handle := openHandle(t, vfs, os.O_RDONLY)
+	buf := make([]byte, 4)
+	n, err := handle.ReadAt(buf, 4)
+	if n != 2 || err != io.EOF || string(buf[:n]) != "ef" {
+		t.Errorf("short ReadAt = %d, %v (%q), want 2, io.EOF", n, err, buf[:n])
+	}
+	if n, err := handle.ReadAt(buf, 1); n != 4 || err != nil {
+		t.Errorf("full ReadAt = %d, %v", n, err)
+	}
+}
+
+func TestFileHandleAppend(t *testing.T) {
+	vfs := newHandleTestFS(t, "ab")
+	handle := openHandle(t, vfs, os.O_WRONLY|os.O_APPEND)
+	if _, err := handle.WriteAt([]byte("x"), 0); err != ErrAppendWriteAt {
+		t.Errorf("WriteAt with O_APPEND: got %v, want ErrAppendWriteAt", err)
+	}
+	handle.Seek(0, io.SeekStart)
+	handle.Write([]byte("cd"))
+	if data, _ := vfs.ReadFile("home/plik.txt"); string(data) != "abcd" {
+		t.Errorf("content %q, want abcd", data)
+	}
+}
+
+func TestFileHandleTruncate(t *testing.T) {
+	vfs := newHandleTestFS(t, "abcdef")
+	handle := openHandle(t, vfs, os.O_RDWR)
+	if err := handle.Truncate(2); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := vfs.ReadFile("home/plik.txt"); string(data) != "ab" {
+		t.Errorf("after shrinking: %q", data)
+	}
+	if err := handle.Truncate(4); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := vfs.ReadFile("home/plik.txt"); !bytes.Equal(data, []byte("ab\x00\x00")) {
+		t.Errorf("after growing: %q", data)
+	}
+	if info, _ := handle.Stat(); info.Size() != 4 {
+		t.Errorf("size %d, want 4", info.Size())
+	}
+}
+
+func TestFileHandleOpenFlags(t *testing.T) {
+	vfs := newHandleTestFS(t, "abc")
+	if _, err := vfs.OpenFile("/home/plik.txt", os.O_RDWR|os.O_CREATE|os.O_EXCL); err != ErrItemExists {
+		t.Errorf("O_EXCL on an existing file: got %v, want ErrItemExists", err)
+	}
+	openHandle(t, vfs, os.O_WRONLY|os.O_TRUNC)
+	if data, _ := vfs.ReadFile("home/plik.txt"); len(data) != 0 {
+		t.Errorf("O_TRUNC left %q", data)
+	}
+	handle := openHandle(t, vfs, os.O_RDONLY)
+	if _, err := handle.Write([]byte("x")); err != ErrNotWritable {
+		t.Errorf("Write on a read-only handle: got %v, want ErrNotWritable", err)
+	}
+	handle.Close()
+	if err := handle.Close(); err != ErrClosed {
+		t.Errorf("second Close: got %v, want ErrClosed", err)
+	}
+}
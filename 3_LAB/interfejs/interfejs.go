@@ -2,6 +2,7 @@ package interfejs
 
 import (
 	"errors"
+	"os"
 	"time"
 )
 
@@ -12,6 +13,10 @@ type FileSystemItem interface {
 	Size() int64
 	CreatedAt() time.Time
 	ModifiedAt() time.Time
+	// Uprawnienia rwx oraz właściciel i grupa obiektu
+	Perm() os.FileMode
+	Owner() int
+	Group() int
 }
 
 // Interfejs definiujący obiekty które mogą być odczytywane
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	_ fs.FS         = (*VirtualFileSystem)(nil)
+	_ fs.ReadDirFS  = (*VirtualFileSystem)(nil)
+	_ fs.StatFS     = (*VirtualFileSystem)(nil)
+	_ fs.ReadFileFS = (*VirtualFileSystem)(nil)
+)
+
+// ItemInfo exposes a FileSystemItem as an fs.FileInfo and an fs.DirEntry.
+type ItemInfo struct {
+	FileSystemItem
+}
+
+func (i ItemInfo) Mode() fs.FileMode {
+	switch i.FileSystemItem.(type) {
+	case Directory:
+		return fs.ModeDir | i.Perm()
+	case *SymLink:
+		return fs.ModeSymlink | i.Perm()
+	}
+	return i.Perm()
+}
+
+func (i ItemInfo) ModTime() time.Time {
+	return i.ModifiedAt()
+}
+
+func (i ItemInfo) IsDir() bool {
+	return i.Mode().IsDir()
+}
+
+func (i ItemInfo) Sys() any {
+	return i.FileSystemItem
+}
+
+func (i ItemInfo) Type() fs.FileMode {
+	return i.Mode().Type()
+}
+
+func (i ItemInfo) Info() (fs.FileInfo, error) {
+	return i, nil
+}
+
+// vfsPath turns an io/fs name, which is relative to the root and never starts with a
+// slash, into a path of the virtual file system.
+func vfsPath(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return "/", nil
+	}
+	return "/" + name, nil
+}
+
+// fsError wraps an error of the virtual file system in an *fs.PathError whose Err is the
+// matching io/fs error, so errors.Is(err, fs.ErrNotExist) and friends work.
+func fsError(op, name string, err error) error {
+	switch err {
+	case ErrItemNotFound, ErrNotDirectory, ErrDanglingLink:
+		err = fs.ErrNotExist
+	case ErrItemExists:
+		err = fs.ErrExist
+	case ErrPermissionDenied:
+		err = fs.ErrPermission
+	case ErrClosed:
+		err = fs.ErrClosed
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+func (vfs *VirtualFileSystem) lookup(op, name string) (FileSystemItem, error) {
+	p, err := vfsPath(op, name)
+	if err != nil {
+		return nil, err
+	}
+	item, err := vfs.FindItem(p)
+	if err != nil {
+		return nil, fsError(op, name, err)
+	}
+	return item, nil
+}
+
+func (vfs *VirtualFileSystem) resolve(op, name string) (FileSystemItem, error) {
+	p, err := vfsPath(op, name)
+	if err != nil {
+		return nil, err
+	}
+	item, err := vfs.ResolveItem(p)
+	if err != nil {
+		return nil, fsError(op, name, err)
+	}
+	return item, nil
+}
+
+// Open opens the named file or directory for reading, as required by fs.FS.
+func (vfs *VirtualFileSystem) Open(name string) (fs.File, error) {
+	item, err := vfs.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if dir, ok := item.(Directory); ok {
+		if err := vfs.checkAccess(dir, accessRead); err != nil {
+			return nil, fsError("open", name, err)
+		}
+		return &dirHandle{dir: dir, name: name}, nil
+	}
+	handle, err := vfs.openItem(item, os.O_RDONLY)
+	if err != nil {
+		return nil, fsError("open", name, err)
+	}
+	return handle, nil
+}
+
+func (vfs *VirtualFileSystem) Stat(name string) (fs.FileInfo, error) {
+	item, err := vfs.resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return ItemInfo{item}, nil
+}
+
+// Lstat and ReadLink make up fs.ReadLinkFS (Go 1.25), so fs.Sub and fs.Lstat see links
+// as links instead of the items they point to.
+func (vfs *VirtualFileSystem) Lstat(name string) (fs.FileInfo, error) {
+	item, err := vfs.lookup("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	return ItemInfo{item}, nil
+}
+
+// ReadLink returns the target of the named link as it was created, so a relative target
+// stays relative to the directory of the link.
+func (vfs *VirtualFileSystem) ReadLink(name string) (string, error) {
+	item, err := vfs.lookup("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	link, ok := item.(*SymLink)
+	if !ok {
+		return "", fsError("readlink", name, fs.ErrInvalid)
+	}
+	return link.Target(), nil
+}
+
+// ReadDir returns the entries of the named directory sorted by name.
+func (vfs *VirtualFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	item, err := vfs.resolve("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	dir, ok := item.(Directory)
+	if !ok {
+		return nil, fsError("readdir", name, ErrNotDirectory)
+	}
+	if err := vfs.checkAccess(dir, accessRead); err != nil {
+		return nil, fsError("readdir", name, err)
+	}
+	return sortedEntries(dir), nil
+}
+
+func (vfs *VirtualFileSystem) ReadFile(name string) ([]byte, error) {
+	item, err := vfs.resolve("read", name)
+	if err != nil {
+		return nil, err
+	}
+	file, ok := item.(fileContent)
+	if !ok {
+		return nil, fsError("read", name, ErrIsDirectory)
+	}
+	if err := vfs.checkAccess(file, accessRead); err != nil {
+		return nil, fsError("read", name, err)
+	}
+	return slices.Clone(file.contentBytes()), nil
+}
+
+func sortedEntries(dir Directory) []fs.DirEntry {
+	items := dir.Items()
+	slices.SortFunc(items, func(a, b FileSystemItem) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	entries := make([]fs.DirEntry, len(items))
+	for i, item := range items {
+		entries[i] = ItemInfo{item}
+	}
+	return entries
+}
+
+// dirHandle is an open directory of the fs.FS view.
+type dirHandle struct {
+	dir     Directory
+	name    string
+	entries []fs.DirEntry
+	offset  int
+	closed  bool
+}
+
+func (d *dirHandle) Stat() (fs.FileInfo, error) {
+	if d.closed {
+		return nil, fsError("stat", d.name, ErrClosed)
+	}
+	return ItemInfo{d.dir}, nil
+}
+
+func (d *dirHandle) Read(b []byte) (int, error) {
+	return 0, fsError("read", d.name, ErrIsDirectory)
+}
+
+func (d *dirHandle) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.closed {
+		return nil, fsError("readdir", d.name, ErrClosed)
+	}
+	if d.entries == nil {
+		d.entries = sortedEntries(d.dir)
+	}
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(remaining))
+	d.offset += n
+	return remaining[:n], nil
+}
+
+func (d *dirHandle) Close() error {
+	if d.closed {
+		return fsError("close", d.name, ErrClosed)
+	}
+	d.closed = true
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestFS(t *testing.T) *VirtualFileSystem {
+	t.Helper()
+	vfs := NewVirtualFileSystem()
+	notes, err := vfs.CreateFile("/home/user", "notatki.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes.(*Plik).Write([]byte("pierwsza linia\n"))
+	if _, err := vfs.CreateReadOnlyFile("/home/user", "readonly.txt", []byte("tylko do odczytu")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vfs.CreateDirectory("/tmp", "pusty"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vfs.CreateSymLink("/home", "link.txt", "user/readonly.txt"); err != nil {
+		t.Fatal(err)
+	}
+	return vfs
+}
+
+func TestFS(t *testing.T) {
+	vfs := newTestFS(t)
+	if err := fstest.TestFS(vfs, "home/user/notatki.txt", "home/user/readonly.txt", "home/link.txt", "tmp/pusty"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFSErrors(t *testing.T) {
+	vfs := newTestFS(t)
+	if _, err := vfs.Open("home/brak.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open of a missing file: got %v, want fs.ErrNotExist", err)
+	}
+	if _, err := vfs.Open("/home"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("Open of an absolute name: got %v, want fs.ErrInvalid", err)
+	}
+	if _, err := vfs.ReadFile("home"); err == nil {
+		t.Error("ReadFile of a directory succeeded")
+	}
+	data, err := fs.ReadFile(vfs, "home/link.txt")
+	if err != nil || string(data) != "tylko do odczytu" {
+		t.Errorf("ReadFile through a link: got %q, %v", data, err)
+	}
+}
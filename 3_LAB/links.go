@@ -0,0 +1,140 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+// maxLinkHops bounds how many links a single lookup may follow, like SYMLOOP_MAX, so a
+// cycle of links ends in ErrTooManyLinks instead of recursing forever.
+const maxLinkHops = 40
+
+func joinPath(dir, name string) string {
+	if !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+	return dir + name
+}
+
+// targetPath returns the absolute path the link points to. Relative targets are
+// resolved against the directory that holds the link.
+func (s *SymLink) targetPath() string {
+	if strings.HasPrefix(s.target, "/") {
+		return path.Clean(s.target)
+	}
+	return path.Join(path.Dir(s.path), s.target)
+}
+
+func childNamed(dir Directory, name string) FileSystemItem {
+	for _, item := range dir.Items() {
+		if item.Name() == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// walkDirs descends through the directories named by parts, following links on the way
+// and creating missing directories when create is set.
+func (vfs *VirtualFileSystem) walkDirs(parts []string, create bool, hops *int) (Directory, error) {
+	var currentDir Directory = vfs.root
+	for _, part := range parts {
+		if err := vfs.checkAccess(currentDir, accessExec); err != nil {
+			return nil, err
+		}
+
+		item := childNamed(currentDir, part)
+		if item == nil {
+			if !create {
+				return nil, ErrItemNotFound
+			}
+			if err := vfs.checkAccess(currentDir, accessWrite); err != nil {
+				return nil, err
+			}
+			newDir := NewKatalog(part, joinPath(currentDir.Path(), part))
+			vfs.own(newDir)
+			if err := currentDir.AddItem(newDir); err != nil {
+				return nil, err
+			}
+			currentDir = newDir
+			continue
+		}
+
+		item, err := vfs.follow(item, hops)
+		if err != nil {
+			return nil, err
+		}
+		dir, ok := item.(Directory)
+		if !ok {
+			return nil, ErrNotDirectory
+		}
+		currentDir = dir
+	}
+	return currentDir, nil
+}
+
+// resolvePath finds the item at itemPath, following links in every directory on the way.
+// A link in the last component is only followed when followLast is set, which is the
+// difference between Stat and Lstat.
+func (vfs *VirtualFileSystem) resolvePath(itemPath string, followLast bool, hops *int) (FileSystemItem, error) {
+	parts := splitPath(itemPath)
+	if len(parts) == 0 {
+		return vfs.root, nil
+	}
+
+	dir, err := vfs.walkDirs(parts[:len(parts)-1], false, hops)
+	if err != nil {
+		return nil, err
+	}
+	if err := vfs.checkAccess(dir, accessExec); err != nil {
+		return nil, err
+	}
+	item := childNamed(dir, parts[len(parts)-1])
+	if item == nil {
+		return nil, ErrItemNotFound
+	}
+	if followLast {
+		return vfs.follow(item, hops)
+	}
+	return item, nil
+}
+
+// follow returns the item a chain of links ends at, or item itself when it is not a
+// link. A link whose target does not exist is reported as ErrDanglingLink.
+func (vfs *VirtualFileSystem) follow(item FileSystemItem, hops *int) (FileSystemItem, error) {
+	for {
+		link, ok := item.(*SymLink)
+		if !ok {
+			return item, nil
+		}
+		*hops++
+		if *hops > maxLinkHops {
+			return nil, ErrTooManyLinks
+		}
+		next, err := vfs.resolvePath(link.targetPath(), false, hops)
+		if err == ErrItemNotFound {
+			return nil, ErrDanglingLink
+		}
+		if err != nil {
+			return nil, err
+		}
+		item = next
+	}
+}
+
+// IsDangling reports whether the item at path is a link that does not lead to an
+// existing item.
+func (vfs *VirtualFileSystem) IsDangling(path string) (bool, error) {
+	item, err := vfs.FindItem(path)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := item.(*SymLink); !ok {
+		return false, nil
+	}
+	_, err = vfs.follow(item, new(int))
+	if err == ErrDanglingLink {
+		return true, nil
+	}
+	return false, err
+}
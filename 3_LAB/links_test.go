@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSymLinkResolution(t *testing.T) {
+	vfs := NewVirtualFileSystem()
+	if _, err := vfs.CreateReadOnlyFile("/home/users", "readonly.txt", []byte("dane")); err != nil {
+		t.Fatal(err)
+	}
+	links := []struct{ dir, name, target string }{
+		{"/home", "link_do_users", "users"},
+		{"/", "home_abs", "/home"},
+		{"/tmp", "przez_link", "../home_abs/link_do_users/readonly.txt"},
+		{"/tmp", "petla_a", "petla_b"},
+		{"/tmp", "petla_b", "/tmp/petla_a"},
+		{"/tmp", "zerwany", "brak.txt"},
+	}
+	for _, link := range links {
+		if _, err := vfs.CreateSymLink(link.dir, link.name, link.target); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, p := range []string{"/home/link_do_users/readonly.txt", "/home_abs/users/readonly.txt", "/tmp/przez_link"} {
+		item, err := vfs.ResolveItem(p)
+		if err != nil {
+			t.Errorf("ResolveItem(%q): %v", p, err)
+			continue
+		}
+		if item.Path() != "/home/users/readonly.txt" {
+			t.Errorf("ResolveItem(%q) = %s, want /home/users/readonly.txt", p, item.Path())
+		}
+	}
+
+	item, err := vfs.FindItem("/tmp/przez_link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := item.(*SymLink); !ok {
+		t.Errorf("FindItem followed the last link, got %T", item)
+	}
+	if target, err := vfs.ReadLink("tmp/przez_link"); err != nil || target != "../home_abs/link_do_users/readonly.txt" {
+		t.Errorf("ReadLink = %q, %v", target, err)
+	}
+
+	if _, err := vfs.ResolveItem("/tmp/petla_a"); err != ErrTooManyLinks {
+		t.Errorf("ResolveItem of a loop: got %v, want ErrTooManyLinks", err)
+	}
+	if _, err := vfs.ResolveItem("/tmp/zerwany"); err != ErrDanglingLink {
+		t.Errorf("ResolveItem of a dangling link: got %v, want ErrDanglingLink", err)
+	}
+	if dangling, err := vfs.IsDangling("/tmp/zerwany"); !dangling || err != nil {
+		t.Errorf("IsDangling(/tmp/zerwany) = %v, %v", dangling, err)
+	}
+	if dangling, err := vfs.IsDangling("/tmp/przez_link"); dangling || err != nil {
+		t.Errorf("IsDangling(/tmp/przez_link) = %v, %v", dangling, err)
+	}
+
+	if err := vfs.Chmod("/tmp/przez_link", 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := vfs.Chown("/tmp/przez_link", 1000, 1000); err != nil {
+		t.Fatal(err)
+	}
+	target, _ := vfs.ResolveItem("/tmp/przez_link")
+	link, _ := vfs.FindItem("/tmp/przez_link")
+	if target.Perm() != 0o600 || target.Owner() != 1000 || link.Perm() != 0o777 || link.Owner() != 0 {
+		t.Errorf("Chmod and Chown changed the link instead of its target")
+	}
+	if err := vfs.Lchown("/tmp/przez_link", 1001, -1); err != nil {
+		t.Fatal(err)
+	}
+	if link.Owner() != 1001 || target.Owner() != 1000 {
+		t.Errorf("Lchown changed the target instead of the link")
+	}
+
+	file, err := vfs.CreateFile("/home/link_do_users", "nowy.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Path() != "/home/users/nowy.txt" {
+		t.Errorf("file created through a link has path %s", file.Path())
+	}
+
+	handle, err := vfs.OpenFile("/home/link_do_users/otwarty.txt", os.O_RDWR|os.O_CREATE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle.Close()
+	if opened, _ := vfs.FindItem("/home/users/otwarty.txt"); opened == nil || opened.Path() != "/home/users/otwarty.txt" {
+		t.Errorf("file created by OpenFile through a link: %v", opened)
+	}
+	if err := vfs.DeleteItem("/home/link_do_users"); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := vfs.Open("home/users/otwarty.txt"); err != nil {
+		t.Errorf("Open after deleting the link: %v", err)
+	} else {
+		f.Close()
+	}
+}
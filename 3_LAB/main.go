@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"time"
 )
@@ -12,6 +14,9 @@ type FileSystemItem interface {
 	Size() int64
 	CreatedAt() time.Time
 	ModifiedAt() time.Time
+	Perm() os.FileMode
+	Owner() int
+	Group() int
 }
 
 type Readable interface {
@@ -36,6 +41,16 @@ var (
 	ErrPermissionDenied = fmt.Errorf("permission denied")
 	ErrNotDirectory     = fmt.Errorf("not a directory")
 	ErrIsDirectory      = fmt.Errorf("is a directory")
+	ErrClosed           = fmt.Errorf("file already closed")
+	ErrNotReadable      = fmt.Errorf("file not opened for reading")
+	ErrNotWritable      = fmt.Errorf("file not opened for writing")
+	ErrInvalidOffset    = fmt.Errorf("invalid offset")
+	ErrAppendWriteAt    = fmt.Errorf("WriteAt on a file opened with O_APPEND")
+	ErrDanglingLink     = fmt.Errorf("dangling symbolic link")
+	ErrTooManyLinks     = fmt.Errorf("too many levels of symbolic links")
+	ErrIntoItself       = fmt.Errorf("cannot move or copy a directory into itself")
+	ErrNotEmpty         = fmt.Errorf("directory not empty")
+	ErrInvalidName      = fmt.Errorf("invalid name")
 )
 
 type BaseItem struct {
@@ -44,6 +59,9 @@ type BaseItem struct {
 	size       int64
 	createdAt  time.Time
 	modifiedAt time.Time
+	perm       os.FileMode
+	uid        int
+	gid        int
 }
 
 func (b *BaseItem) Name() string {
@@ -70,9 +88,36 @@ func (b *BaseItem) setModifiedAt() {
 	b.modifiedAt = time.Now()
 }
 
+func (b *BaseItem) Perm() os.FileMode {
+	return b.perm
+}
+
+func (b *BaseItem) Owner() int {
+	return b.uid
+}
+
+func (b *BaseItem) Group() int {
+	return b.gid
+}
+
+func (b *BaseItem) chmod(perm os.FileMode) {
+	b.perm = perm & os.ModePerm
+}
+
+func (b *BaseItem) chown(uid, gid int) {
+	b.uid = uid
+	b.gid = gid
+}
+
+func (b *BaseItem) relocate(name, path string) {
+	b.name = name
+	b.path = path
+}
+
 type Plik struct {
 	BaseItem
-	content []byte
+	content    []byte
+	readOffset int64
 }
 
 func NewPlik(name, path string) *Plik {
@@ -84,27 +129,33 @@ func NewPlik(name, path string) *Plik {
 			size:       0,
 			createdAt:  now,
 			modifiedAt: now,
+			perm:       0o644,
 		},
 		content: []byte{},
 	}
 }
 
 func (p *Plik) Read(b []byte) (n int, err error) {
-	if len(p.content) == 0 {
-		return 0, nil
-	}
-
-	n = copy(b, p.content)
-	return n, nil
+	n, err = readContent(p.content, b, p.readOffset)
+	p.readOffset += int64(n)
+	return n, err
 }
 
 func (p *Plik) Write(b []byte) (n int, err error) {
-	p.content = append(p.content, b...)
-	p.size = int64(len(p.content))
-	p.setModifiedAt()
+	p.setContent(append(p.content, b...))
 	return len(b), nil
 }
 
+func (p *Plik) contentBytes() []byte {
+	return p.content
+}
+
+func (p *Plik) setContent(content []byte) {
+	p.content = content
+	p.size = int64(len(content))
+	p.setModifiedAt()
+}
+
 type Katalog struct {
 	BaseItem
 	items map[string]FileSystemItem
@@ -119,6 +170,7 @@ func NewKatalog(name, path string) *Katalog {
 			size:       0,
 			createdAt:  now,
 			modifiedAt: now,
+			perm:       0o755,
 		},
 		items: make(map[string]FileSystemItem),
 	}
@@ -154,30 +206,32 @@ func (k *Katalog) Items() []FileSystemItem {
 
 type SymLink struct {
 	BaseItem
-	target FileSystemItem
+	target string
 }
 
-func NewSymLink(name, path string, target FileSystemItem) *SymLink {
+func NewSymLink(name, path string, target string) *SymLink {
 	now := time.Now()
 	return &SymLink{
 		BaseItem: BaseItem{
 			name:       name,
 			path:       path,
-			size:       0,
+			size:       int64(len(target)),
 			createdAt:  now,
 			modifiedAt: now,
+			perm:       0o777,
 		},
 		target: target,
 	}
 }
 
-func (s *SymLink) Target() FileSystemItem {
+func (s *SymLink) Target() string {
 	return s.target
 }
 
 type PlikDoOdczytu struct {
 	BaseItem
-	content []byte
+	content    []byte
+	readOffset int64
 }
 
 func NewPlikDoOdczytu(name, path string, content []byte) *PlikDoOdczytu {
@@ -189,27 +243,41 @@ func NewPlikDoOdczytu(name, path string, content []byte) *PlikDoOdczytu {
 			size:       int64(len(content)),
 			createdAt:  now,
 			modifiedAt: now,
+			perm:       0o444,
 		},
 		content: content,
 	}
 }
 
 func (p *PlikDoOdczytu) Read(b []byte) (n int, err error) {
-	if len(p.content) == 0 {
+	n, err = readContent(p.content, b, p.readOffset)
+	p.readOffset += int64(n)
+	return n, err
+}
+
+func (p *PlikDoOdczytu) contentBytes() []byte {
+	return p.content
+}
+
+func readContent(content []byte, b []byte, offset int64) (int, error) {
+	if len(b) == 0 {
 		return 0, nil
 	}
-
-	n = copy(b, p.content)
-	return n, nil
+	if offset >= int64(len(content)) {
+		return 0, io.EOF
+	}
+	return copy(b, content[offset:]), nil
 }
 
 type VirtualFileSystem struct {
 	root *Katalog
+	user User
 }
 
 func NewVirtualFileSystem() *VirtualFileSystem {
 	return &VirtualFileSystem{
 		root: NewKatalog("root", "/"),
+		user: Root,
 	}
 }
 
@@ -227,45 +295,7 @@ func splitPath(path string) []string {
 }
 
 func (vfs *VirtualFileSystem) getOrCreateDirPath(path string, createIfNotExist bool) (Directory, error) {
-	if path == "/" {
-		return vfs.root, nil
-	}
-
-	parts := splitPath(path)
-	var currentDir Directory = vfs.root
-
-	for i, part := range parts {
-		currentPath := "/" + strings.Join(parts[:i+1], "/")
-		items := currentDir.Items()
-		found := false
-
-		for _, item := range items {
-			if item.Name() == part {
-				if dir, ok := item.(Directory); ok {
-					currentDir = dir
-					found = true
-					break
-				} else {
-					return nil, ErrNotDirectory
-				}
-			}
-		}
-
-		if !found {
-			if !createIfNotExist {
-				return nil, ErrItemNotFound
-			}
-
-			newDir := NewKatalog(part, currentPath)
-			err := currentDir.AddItem(newDir)
-			if err != nil {
-				return nil, err
-			}
-			currentDir = newDir
-		}
-	}
-
-	return currentDir, nil
+	return vfs.walkDirs(splitPath(path), createIfNotExist, new(int))
 }
 
 func (vfs *VirtualFileSystem) CreateFile(path string, name string) (FileSystemItem, error) {
@@ -274,13 +304,14 @@ func (vfs *VirtualFileSystem) CreateFile(path string, name string) (FileSystemIt
 		return nil, err
 	}
 
-	filePath := path
-	if !strings.HasSuffix(filePath, "/") {
-		filePath += "/"
+	filePath := joinPath(dir.Path(), name)
+
+	if err := vfs.checkAccess(dir, accessWrite|accessExec); err != nil {
+		return nil, err
 	}
-	filePath += name
 
 	file := NewPlik(name, filePath)
+	vfs.own(file)
 	err = dir.AddItem(file)
 	if err != nil {
 		return nil, err
@@ -295,13 +326,14 @@ func (vfs *VirtualFileSystem) CreateReadOnlyFile(path string, name string, conte
 		return nil, err
 	}
 
-	filePath := path
-	if !strings.HasSuffix(filePath, "/") {
-		filePath += "/"
+	filePath := joinPath(dir.Path(), name)
+
+	if err := vfs.checkAccess(dir, accessWrite|accessExec); err != nil {
+		return nil, err
 	}
-	filePath += name
 
 	file := NewPlikDoOdczytu(name, filePath, content)
+	vfs.own(file)
 	err = dir.AddItem(file)
 	if err != nil {
 		return nil, err
@@ -316,13 +348,14 @@ func (vfs *VirtualFileSystem) CreateDirectory(path string, name string) (Directo
 		return nil, err
 	}
 
-	dirPath := path
-	if !strings.HasSuffix(dirPath, "/") {
-		dirPath += "/"
+	dirPath := joinPath(dir.Path(), name)
+
+	if err := vfs.checkAccess(dir, accessWrite|accessExec); err != nil {
+		return nil, err
 	}
-	dirPath += name
 
 	newDir := NewKatalog(name, dirPath)
+	vfs.own(newDir)
 	err = dir.AddItem(newDir)
 	if err != nil {
 		return nil, err
@@ -331,19 +364,20 @@ func (vfs *VirtualFileSystem) CreateDirectory(path string, name string) (Directo
 	return newDir, nil
 }
 
-func (vfs *VirtualFileSystem) CreateSymLink(path string, name string, target FileSystemItem) (FileSystemItem, error) {
+func (vfs *VirtualFileSystem) CreateSymLink(path string, name string, target string) (FileSystemItem, error) {
 	dir, err := vfs.getOrCreateDirPath(path, true)
 	if err != nil {
 		return nil, err
 	}
 
-	linkPath := path
-	if !strings.HasSuffix(linkPath, "/") {
-		linkPath += "/"
+	linkPath := joinPath(dir.Path(), name)
+
+	if err := vfs.checkAccess(dir, accessWrite|accessExec); err != nil {
+		return nil, err
 	}
-	linkPath += name
 
 	symLink := NewSymLink(name, linkPath, target)
+	vfs.own(symLink)
 	err = dir.AddItem(symLink)
 	if err != nil {
 		return nil, err
@@ -353,27 +387,11 @@ func (vfs *VirtualFileSystem) CreateSymLink(path string, name string, target Fil
 }
 
 func (vfs *VirtualFileSystem) FindItem(path string) (FileSystemItem, error) {
-	if path == "/" {
-		return vfs.root, nil
-	}
-
-	parts := splitPath(path)
-	parentPath := "/" + strings.Join(parts[:len(parts)-1], "/")
-	name := parts[len(parts)-1]
-
-	dir, err := vfs.getOrCreateDirPath(parentPath, false)
-	if err != nil {
-		return nil, err
-	}
-
-	items := dir.Items()
-	for _, item := range items {
-		if item.Name() == name {
-			return item, nil
-		}
-	}
+	return vfs.resolvePath(path, false, new(int))
+}
 
-	return nil, ErrItemNotFound
+func (vfs *VirtualFileSystem) ResolveItem(path string) (FileSystemItem, error) {
+	return vfs.resolvePath(path, true, new(int))
 }
 
 func (vfs *VirtualFileSystem) DeleteItem(path string) error {
@@ -389,6 +407,9 @@ func (vfs *VirtualFileSystem) DeleteItem(path string) error {
 	if err != nil {
 		return err
 	}
+	if err := vfs.checkAccess(dir, accessWrite|accessExec); err != nil {
+		return err
+	}
 
 	return dir.RemoveItem(name)
 }
@@ -446,13 +467,30 @@ func main() {
 	}
 	fmt.Printf("Utworzono plik tylko do odczytu: %s\n", readOnlyFile.Path())
 
-	symLink, err := fs.CreateSymLink("/home", "link_do_users", usersDir)
+	symLink, err := fs.CreateSymLink("/home", "link_do_users", "users")
 	if err != nil {
 		fmt.Printf("Błąd podczas tworzenia dowiązania symbolicznego: %v\n", err)
 		return
 	}
 	fmt.Printf("Utworzono dowiązanie symboliczne: %s\n", symLink.Path())
 
+	linkedFile, err := fs.FindItem("/home/link_do_users/readonly.txt")
+	if err != nil {
+		fmt.Printf("Błąd podczas wyszukiwania pliku przez dowiązanie: %v\n", err)
+		return
+	}
+	fmt.Printf("Przez dowiązanie znaleziono plik: %s\n", linkedFile.Path())
+
+	fs.CreateSymLink("/tmp", "petla_a", "petla_b")
+	fs.CreateSymLink("/tmp", "petla_b", "/tmp/petla_a")
+	if _, err := fs.ResolveItem("/tmp/petla_a"); err != nil {
+		fmt.Printf("Nie można rozwiązać /tmp/petla_a: %v\n", err)
+	}
+	fs.CreateSymLink("/tmp", "zerwany", "/nie/istnieje")
+	if dangling, _ := fs.IsDangling("/tmp/zerwany"); dangling {
+		fmt.Println("Dowiązanie /tmp/zerwany wskazuje na nieistniejący element")
+	}
+
 	rootDir, err := fs.FindItem("/")
 	if err != nil {
 		fmt.Printf("Błąd podczas pobierania katalogu głównego: %v\n", err)
@@ -498,4 +536,106 @@ func main() {
 	for _, item := range usersDirAsDirectory.Items() {
 		fmt.Printf("- %s (ścieżka: %s, rozmiar: %d bajtów)\n", item.Name(), item.Path(), item.Size())
 	}
+
+	handle, err := fs.OpenFile("/home/users/notatki.txt", os.O_RDWR|os.O_CREATE)
+	if err != nil {
+		fmt.Printf("Błąd podczas otwierania pliku: %v\n", err)
+		return
+	}
+	defer handle.Close()
+
+	if _, err := io.WriteString(handle, "pierwsza linia\ndruga linia\n"); err != nil {
+		fmt.Printf("Błąd podczas zapisu do pliku: %v\n", err)
+		return
+	}
+	if _, err := handle.Seek(0, io.SeekStart); err != nil {
+		fmt.Printf("Błąd podczas zmiany pozycji w pliku: %v\n", err)
+		return
+	}
+	allContent, err := io.ReadAll(handle)
+	if err != nil {
+		fmt.Printf("Błąd podczas odczytu z pliku: %v\n", err)
+		return
+	}
+	fmt.Printf("\nOdczytano całą zawartość pliku notatki.txt (%d bajtów):\n%s", len(allContent), allContent)
+
+	appendHandle, err := fs.OpenFile("/home/users/notatki.txt", os.O_WRONLY|os.O_APPEND)
+	if err != nil {
+		fmt.Printf("Błąd podczas otwierania pliku: %v\n", err)
+		return
+	}
+	defer appendHandle.Close()
+	io.WriteString(appendHandle, "trzecia linia\n")
+
+	tail := make([]byte, 14)
+	n, err := handle.ReadAt(tail, int64(len(allContent)))
+	if err != nil && err != io.EOF {
+		fmt.Printf("Błąd podczas odczytu z pliku: %v\n", err)
+		return
+	}
+	fmt.Printf("Dopisano na końcu pliku: %s", tail[:n])
+
+	if _, err := fs.OpenFile("/home/users/readonly.txt", os.O_WRONLY); err != nil {
+		fmt.Printf("Nie można otworzyć readonly.txt do zapisu: %v\n", err)
+	}
+
+	jan := User{Name: "jan", UID: 1000, GID: 1000}
+	janFs := fs.AsUser(jan)
+	if err := fs.Chmod("/home/users/notatki.txt", 0o600); err != nil {
+		fmt.Printf("Błąd podczas zmiany uprawnień: %v\n", err)
+		return
+	}
+	if _, err := janFs.OpenFile("/home/users/notatki.txt", os.O_RDONLY); err != nil {
+		fmt.Printf("\nUżytkownik %s nie może odczytać notatki.txt: %v\n", jan.Name, err)
+	}
+	if _, err := janFs.CreateFile("/home/users", "jana.txt"); err != nil {
+		fmt.Printf("Użytkownik %s nie może utworzyć pliku w /home/users: %v\n", jan.Name, err)
+	}
+
+	if err := fs.Chown("/home/users/notatki.txt", jan.UID, jan.GID); err != nil {
+		fmt.Printf("Błąd podczas zmiany właściciela: %v\n", err)
+		return
+	}
+	janHandle, err := janFs.OpenFile("/home/users/notatki.txt", os.O_RDONLY)
+	if err != nil {
+		fmt.Printf("Błąd podczas otwierania pliku: %v\n", err)
+		return
+	}
+	defer janHandle.Close()
+	janContent, _ := io.ReadAll(janHandle)
+	fmt.Printf("Po zmianie właściciela %s odczytał %d bajtów z notatki.txt\n", jan.Name, len(janContent))
+
+	renamed, err := fs.Rename("/home/users/readonly.txt", "tylko_odczyt.txt", NoOverwrite)
+	if err != nil {
+		fmt.Printf("Błąd podczas zmiany nazwy: %v\n", err)
+		return
+	}
+	fmt.Printf("\nZmieniono nazwę na: %s\n", renamed.Path())
+
+	if _, err := fs.CreateDirectory("/", "archiwum"); err != nil {
+		fmt.Printf("Błąd podczas tworzenia katalogu archiwum: %v\n", err)
+		return
+	}
+	movedDir, err := fs.Move("/home/users", "/archiwum", NoOverwrite)
+	if err != nil {
+		fmt.Printf("Błąd podczas przenoszenia katalogu: %v\n", err)
+		return
+	}
+	fmt.Printf("Przeniesiono katalog users do: %s\n", movedDir.Path())
+	for _, item := range movedDir.(Directory).Items() {
+		fmt.Printf("- %s (ścieżka: %s)\n", item.Name(), item.Path())
+	}
+	if _, err := fs.Move("/archiwum", "/archiwum/users", NoOverwrite); err != nil {
+		fmt.Printf("Nie można przenieść /archiwum do /archiwum/users: %v\n", err)
+	}
+
+	copiedDir, err := fs.Copy("/archiwum/users", "/home/kopia_users", true, NoOverwrite)
+	if err != nil {
+		fmt.Printf("Błąd podczas kopiowania katalogu: %v\n", err)
+		return
+	}
+	fmt.Printf("Skopiowano katalog users do: %s\n", copiedDir.Path())
+	if _, err := fs.Copy("/archiwum/users/notatki.txt", "/home/kopia_users/notatki.txt", false, NoOverwrite); err != nil {
+		fmt.Printf("Nie można skopiować notatki.txt bez nadpisywania: %v\n", err)
+	}
 }
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// OverwritePolicy decides what Rename, Move and Copy do when the destination name is
+// already taken.
+type OverwritePolicy int
+
+const (
+	// NoOverwrite fails with ErrItemExists.
+	NoOverwrite OverwritePolicy = iota
+	// Overwrite replaces the existing item. Like rename(2), a directory only replaces an
+	// empty directory and a file never replaces a directory.
+	Overwrite
+	// SkipExisting leaves the existing item in place and returns it.
+	SkipExisting
+)
+
+type relocatable interface {
+	relocate(name, path string)
+}
+
+// isWithin reports whether p is dir or lies below it.
+func isWithin(p, dir string) bool {
+	return p == dir || strings.HasPrefix(p, joinPath(dir, ""))
+}
+
+func parentPath(path string) string {
+	parts := splitPath(path)
+	if len(parts) == 0 {
+		return "/"
+	}
+	return "/" + strings.Join(parts[:len(parts)-1], "/")
+}
+
+// setPaths moves item to path under name and updates the paths of all its descendants.
+func setPaths(item FileSystemItem, name, path string) {
+	item.(relocatable).relocate(name, path)
+	if dir, ok := item.(Directory); ok {
+		for _, child := range dir.Items() {
+			setPaths(child, child.Name(), joinPath(path, child.Name()))
+		}
+	}
+}
+
+// source finds the item at path, without following a final link, and the directory
+// holding it, which the user must be able to modify when remove is set.
+func (vfs *VirtualFileSystem) source(path string, remove bool) (FileSystemItem, Directory, error) {
+	if len(splitPath(path)) == 0 {
+		return nil, nil, ErrPermissionDenied
+	}
+	item, err := vfs.FindItem(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	dir, err := vfs.getOrCreateDirPath(parentPath(path), false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if remove {
+		if err := vfs.checkAccess(dir, accessWrite|accessExec); err != nil {
+			return nil, nil, err
+		}
+	}
+	return item, dir, nil
+}
+
+// destination returns the directory and name that dst stands for. Like mv and cp, an
+// existing directory at dst receives the item under its own name.
+func (vfs *VirtualFileSystem) destination(item FileSystemItem, dst string) (Directory, string, error) {
+	if target, err := vfs.ResolveItem(dst); err == nil {
+		if dir, ok := target.(Directory); ok {
+			return dir, item.Name(), vfs.checkAccess(dir, accessWrite|accessExec)
+		}
+	}
+	parts := splitPath(dst)
+	dir, err := vfs.getOrCreateDirPath(parentPath(dst), false)
+	if err != nil {
+		return nil, "", err
+	}
+	return dir, parts[len(parts)-1], vfs.checkAccess(dir, accessWrite|accessExec)
+}
+
+// makeRoom applies the policy to the item called name in dir. It returns the existing
+// item when it has to be kept.
+func makeRoom(dir Directory, name string, item FileSystemItem, policy OverwritePolicy) (FileSystemItem, error) {
+	existing := childNamed(dir, name)
+	if existing == nil {
+		return nil, nil
+	}
+	switch policy {
+	case SkipExisting:
+		return existing, nil
+	case Overwrite:
+		if existing == item {
+			return nil, ErrItemExists
+		}
+		_, isDir := item.(Directory)
+		existingDir, existingIsDir := existing.(Directory)
+		switch {
+		case existingIsDir && !isDir:
+			return nil, ErrIsDirectory
+		case !existingIsDir && isDir:
+			return nil, ErrNotDirectory
+		case existingIsDir && len(existingDir.Items()) > 0:
+			return nil, ErrNotEmpty
+		}
+		return nil, dir.RemoveItem(name)
+	}
+	return nil, ErrItemExists
+}
+
+// Rename gives the item at path a new name in the same directory.
+func (vfs *VirtualFileSystem) Rename(path string, newName string, policy OverwritePolicy) (FileSystemItem, error) {
+	if newName == "" || newName == "." || newName == ".." || strings.Contains(newName, "/") {
+		return nil, ErrInvalidName
+	}
+	item, dir, err := vfs.source(path, true)
+	if err != nil {
+		return nil, err
+	}
+	return vfs.relocateItem(item, dir, dir, newName, policy)
+}
+
+// Move moves the item at src to dst, or into dst when it is a directory. A link is moved
+// as a link; its relative target is then resolved from the new location.
+func (vfs *VirtualFileSystem) Move(src string, dst string, policy OverwritePolicy) (FileSystemItem, error) {
+	item, from, err := vfs.source(src, true)
+	if err != nil {
+		return nil, err
+	}
+	to, name, err := vfs.destination(item, dst)
+	if err != nil {
+		return nil, err
+	}
+	return vfs.relocateItem(item, from, to, name, policy)
+}
+
+func (vfs *VirtualFileSystem) relocateItem(item FileSystemItem, from, to Directory, name string, policy OverwritePolicy) (FileSystemItem, error) {
+	if to == from && name == item.Name() {
+		return item, nil
+	}
+	if _, ok := item.(Directory); ok && isWithin(to.Path(), item.Path()) {
+		return nil, ErrIntoItself
+	}
+	existing, err := makeRoom(to, name, item, policy)
+	if err != nil || existing != nil {
+		return existing, err
+	}
+
+	if err := from.RemoveItem(item.Name()); err != nil {
+		return nil, err
+	}
+	setPaths(item, name, joinPath(to.Path(), name))
+	if err := to.AddItem(item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Copy copies the item at src to dst, or into dst when it is a directory. Directories
+// are only copied when recursive is set. Links are copied as links, and the copies
+// belong to the user with the permission bits of the originals.
+func (vfs *VirtualFileSystem) Copy(src string, dst string, recursive bool, policy OverwritePolicy) (FileSystemItem, error) {
+	item, _, err := vfs.source(src, false)
+	if err != nil {
+		return nil, err
+	}
+	to, name, err := vfs.destination(item, dst)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := item.(Directory); ok {
+		if !recursive {
+			return nil, ErrIsDirectory
+		}
+		if isWithin(to.Path(), item.Path()) {
+			return nil, ErrIntoItself
+		}
+	}
+
+	copied, err := vfs.copyItem(item, name, joinPath(to.Path(), name))
+	if err != nil {
+		return nil, err
+	}
+	existing, err := makeRoom(to, name, item, policy)
+	if err != nil || existing != nil {
+		return existing, err
+	}
+	if err := to.AddItem(copied); err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
+
+func (vfs *VirtualFileSystem) copyItem(item FileSystemItem, name, path string) (FileSystemItem, error) {
+	var copied FileSystemItem
+	switch it := item.(type) {
+	case Directory:
+		if err := vfs.checkAccess(it, accessRead|accessExec); err != nil {
+			return nil, err
+		}
+		dir := NewKatalog(name, path)
+		for _, child := range it.Items() {
+			childCopy, err := vfs.copyItem(child, child.Name(), joinPath(path, child.Name()))
+			if err != nil {
+				return nil, err
+			}
+			if err := dir.AddItem(childCopy); err != nil {
+				return nil, err
+			}
+		}
+		copied = dir
+	case *SymLink:
+		copied = NewSymLink(name, path, it.Target())
+	case *PlikDoOdczytu:
+		if err := vfs.checkAccess(it, accessRead); err != nil {
+			return nil, err
+		}
+		copied = NewPlikDoOdczytu(name, path, slices.Clone(it.content))
+	case *Plik:
+		if err := vfs.checkAccess(it, accessRead); err != nil {
+			return nil, err
+		}
+		file := NewPlik(name, path)
+		file.setContent(slices.Clone(it.content))
+		copied = file
+	default:
+		return nil, ErrNotImplemented
+	}
+	copied.(permissioned).chmod(item.Perm())
+	vfs.own(copied)
+	return copied, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func newMoveTestFS(t *testing.T) *VirtualFileSystem {
+	t.Helper()
+	vfs := NewVirtualFileSystem()
+	for _, dir := range []string{"/a/b/c", "/d"} {
+		if _, err := vfs.getOrCreateDirPath(dir, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file, err := vfs.CreateFile("/a/b/c", "plik.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.(*Plik).Write([]byte("dane"))
+	if _, err := vfs.CreateFile("/d", "plik.txt"); err != nil {
+		t.Fatal(err)
+	}
+	return vfs
+}
+
+func TestMove(t *testing.T) {
+	vfs := newMoveTestFS(t)
+	moved, err := vfs.Move("/a/b", "/d", NoOverwrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Path() != "/d/b" {
+		t.Errorf("moved directory has path %s, want /d/b", moved.Path())
+	}
+	file, err := vfs.FindItem("/d/b/c/plik.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Path() != "/d/b/c/plik.txt" {
+		t.Errorf("descendant has path %s, want /d/b/c/plik.txt", file.Path())
+	}
+	if _, err := vfs.FindItem("/a/b"); err != ErrItemNotFound {
+		t.Errorf("old path still found: %v", err)
+	}
+
+	if _, err := vfs.Move("/d", "/d/b/c", NoOverwrite); err != ErrIntoItself {
+		t.Errorf("Move into a descendant: got %v, want ErrIntoItself", err)
+	}
+	if _, err := vfs.Move("/", "/d", NoOverwrite); err != ErrPermissionDenied {
+		t.Errorf("Move of the root: got %v, want ErrPermissionDenied", err)
+	}
+
+	renamed, err := vfs.Rename("/d/b/c", "e", NoOverwrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.Path() != "/d/b/e" || file.Path() != "/d/b/e/plik.txt" {
+		t.Errorf("after Rename: %s and %s", renamed.Path(), file.Path())
+	}
+	if _, err := vfs.Rename("/d/b/e", "a/b", NoOverwrite); err != ErrInvalidName {
+		t.Errorf("Rename to a path: got %v, want ErrInvalidName", err)
+	}
+}
+
+func TestMoveOverwritePolicy(t *testing.T) {
+	vfs := newMoveTestFS(t)
+	if _, err := vfs.Move("/a/b/c/plik.txt", "/d/plik.txt", NoOverwrite); err != ErrItemExists {
+		t.Errorf("NoOverwrite: got %v, want ErrItemExists", err)
+	}
+	kept, err := vfs.Move("/a/b/c/plik.txt", "/d/plik.txt", SkipExisting)
+	if err != nil || kept.Size() != 0 {
+		t.Errorf("SkipExisting: got %v with size %d, want the existing empty file", err, kept.Size())
+	}
+	if _, err := vfs.Move("/a/b/c/plik.txt", "/d/plik.txt", Overwrite); err != nil {
+		t.Fatal(err)
+	}
+	replaced, err := vfs.FindItem("/d/plik.txt")
+	if err != nil || replaced.Size() != 4 {
+		t.Errorf("Overwrite: got %v with size %d, want the moved file", err, replaced.Size())
+	}
+	if _, err := vfs.Move("/d/plik.txt", "/a/b", Overwrite); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vfs.Move("/a/b/plik.txt", "/a/b/c", Overwrite); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vfs.CreateFile("/a/x", "plik.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vfs.Rename("/a/b", "x", Overwrite); err != ErrNotEmpty {
+		t.Errorf("Overwrite of a non-empty directory: got %v, want ErrNotEmpty", err)
+	}
+	if _, err := vfs.CreateFile("/a", "f.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vfs.Rename("/a/f.txt", "x", Overwrite); err != ErrIsDirectory {
+		t.Errorf("Overwrite of a directory by a file: got %v, want ErrIsDirectory", err)
+	}
+	if _, err := vfs.CreateDirectory("/a", "pusty"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vfs.Rename("/a/b", "pusty", Overwrite); err != nil {
+		t.Fatalf("Overwrite of an empty directory: %v", err)
+	}
+	if _, err := vfs.FindItem("/a/pusty/c/plik.txt"); err != nil {
+		t.Errorf("after replacing an empty directory: %v", err)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	vfs := newMoveTestFS(t)
+	if _, err := vfs.CreateSymLink("/a/b", "link", "c/plik.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vfs.Copy("/a", "/kopia", false, NoOverwrite); err != ErrIsDirectory {
+		t.Errorf("Copy of a directory without recursive: got %v, want ErrIsDirectory", err)
+	}
+	if _, err := vfs.Copy("/a", "/a/b/c", true, NoOverwrite); err != ErrIntoItself {
+		t.Errorf("Copy into a descendant: got %v, want ErrIntoItself", err)
+	}
+	copied, err := vfs.Copy("/a", "/kopia", true, NoOverwrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied.Path() != "/kopia" {
+		t.Errorf("copy has path %s, want /kopia", copied.Path())
+	}
+
+	handle, err := vfs.OpenFile("/kopia/b/link", os.O_WRONLY|os.O_APPEND)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle.Write([]byte(" kopii"))
+	original, _ := vfs.ResolveItem("/a/b/link")
+	copiedFile, _ := vfs.ResolveItem("/kopia/b/link")
+	if string(original.(*Plik).content) != "dane" || string(copiedFile.(*Plik).content) != "dane kopii" {
+		t.Errorf("copy shares content: %q and %q", original.(*Plik).content, copiedFile.(*Plik).content)
+	}
+	if copiedFile.Path() != "/kopia/b/c/plik.txt" {
+		t.Errorf("relative link of the copy resolves to %s", copiedFile.Path())
+	}
+
+	if _, err := vfs.Copy("/a", "/kopia", true, NoOverwrite); err != nil {
+		t.Errorf("Copy into an existing directory: %v", err)
+	}
+	if _, err := vfs.FindItem("/kopia/a/b/c/plik.txt"); err != nil {
+		t.Errorf("Copy into an existing directory: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"slices"
+)
+
+// User is the identity operations of the file system run as. Root, with UID 0, passes
+// every permission check.
+type User struct {
+	Name   string
+	UID    int
+	GID    int
+	Groups []int
+}
+
+var Root = User{Name: "root"}
+
+func (u User) inGroup(gid int) bool {
+	return u.GID == gid || slices.Contains(u.Groups, gid)
+}
+
+const (
+	accessExec  os.FileMode = 1
+	accessWrite os.FileMode = 2
+	accessRead  os.FileMode = 4
+)
+
+type permissioned interface {
+	chmod(perm os.FileMode)
+	chown(uid, gid int)
+}
+
+// AsUser returns a view of the same file system whose operations run as user. Items
+// created through the view belong to the user and its primary group.
+func (vfs *VirtualFileSystem) AsUser(user User) *VirtualFileSystem {
+	return &VirtualFileSystem{root: vfs.root, user: user}
+}
+
+func (vfs *VirtualFileSystem) User() User {
+	return vfs.user
+}
+
+// checkAccess reports ErrPermissionDenied unless the user has every access bit in want
+// on item, using the owner, group or other bits like Unix does.
+func (vfs *VirtualFileSystem) checkAccess(item FileSystemItem, want os.FileMode) error {
+	if vfs.user.UID == Root.UID {
+		return nil
+	}
+	perm := item.Perm()
+	switch {
+	case item.Owner() == vfs.user.UID:
+		perm >>= 6
+	case vfs.user.inGroup(item.Group()):
+		perm >>= 3
+	}
+	if perm&want != want {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+func (vfs *VirtualFileSystem) own(item FileSystemItem) {
+	item.(permissioned).chown(vfs.user.UID, vfs.user.GID)
+}
+
+// Chmod changes the permission bits of the item at path, following a link in the last
+// component like chmod(2). Only the owner of the item and root may change them.
+func (vfs *VirtualFileSystem) Chmod(path string, perm os.FileMode) error {
+	item, err := vfs.ResolveItem(path)
+	if err != nil {
+		return err
+	}
+	if vfs.user.UID != Root.UID && vfs.user.UID != item.Owner() {
+		return ErrPermissionDenied
+	}
+	item.(permissioned).chmod(perm)
+	return nil
+}
+
+// Chown changes the owner and group of the item at path, following a link in the last
+// component; -1 keeps the current value, as in os.Chown. Only root may give an item
+// away, while its owner may change the group to one they belong to.
+func (vfs *VirtualFileSystem) Chown(path string, uid, gid int) error {
+	item, err := vfs.ResolveItem(path)
+	if err != nil {
+		return err
+	}
+	return vfs.chown(item, uid, gid)
+}
+
+// Lchown is Chown for the link itself when the last component of path is a link.
+func (vfs *VirtualFileSystem) Lchown(path string, uid, gid int) error {
+	item, err := vfs.FindItem(path)
+	if err != nil {
+		return err
+	}
+	return vfs.chown(item, uid, gid)
+}
+
+func (vfs *VirtualFileSystem) chown(item FileSystemItem, uid, gid int) error {
+	if uid == -1 {
+		uid = item.Owner()
+	}
+	if gid == -1 {
+		gid = item.Group()
+	}
+	if vfs.user.UID != Root.UID {
+		if item.Owner() != vfs.user.UID || uid != item.Owner() {
+			return ErrPermissionDenied
+		}
+		if gid != item.Group() && !vfs.user.inGroup(gid) {
+			return ErrPermissionDenied
+		}
+	}
+	item.(permissioned).chown(uid, gid)
+	return nil
+}
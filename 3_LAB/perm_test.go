@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPermissions(t *testing.T) {
+	vfs := NewVirtualFileSystem()
+	if _, err := vfs.CreateDirectory("/", "home"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vfs.CreateFile("/home", "root.txt"); err != nil {
+		t.Fatal(err)
+	}
+	jan := vfs.AsUser(User{Name: "jan", UID: 1000, GID: 100})
+	ewa := vfs.AsUser(User{Name: "ewa", UID: 1001, GID: 200, Groups: []int{100}})
+
+	if _, err := jan.CreateFile("/home", "jan.txt"); err != ErrPermissionDenied {
+		t.Errorf("CreateFile in a directory of root: got %v, want ErrPermissionDenied", err)
+	}
+	if err := vfs.Chmod("/home", 0o777); err != nil {
+		t.Fatal(err)
+	}
+	file, err := jan.CreateFile("/home", "jan.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Owner() != 1000 || file.Group() != 100 || file.Perm() != 0o644 {
+		t.Errorf("new file: owner %d, group %d, perm %v", file.Owner(), file.Group(), file.Perm())
+	}
+
+	if _, err := ewa.OpenFile("/home/jan.txt", os.O_RDONLY); err != nil {
+		t.Errorf("read through group bits: %v", err)
+	}
+	if _, err := ewa.OpenFile("/home/jan.txt", os.O_RDWR); err != ErrPermissionDenied {
+		t.Errorf("write without write bits: got %v, want ErrPermissionDenied", err)
+	}
+	if err := ewa.Chmod("/home/jan.txt", 0o666); err != ErrPermissionDenied {
+		t.Errorf("Chmod by another user: got %v, want ErrPermissionDenied", err)
+	}
+	if err := jan.Chown("/home/jan.txt", 1001, -1); err != ErrPermissionDenied {
+		t.Errorf("Chown to another user: got %v, want ErrPermissionDenied", err)
+	}
+	if err := jan.Chown("/home/jan.txt", -1, 200); err != ErrPermissionDenied {
+		t.Errorf("Chown to a group of another user: got %v, want ErrPermissionDenied", err)
+	}
+
+	if err := jan.Chmod("/home/jan.txt", 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ewa.OpenFile("/home/jan.txt", os.O_RDONLY); err != ErrPermissionDenied {
+		t.Errorf("read after Chmod 0600: got %v, want ErrPermissionDenied", err)
+	}
+	if _, err := vfs.OpenFile("/home/jan.txt", os.O_RDWR); err != nil {
+		t.Errorf("root ignores permissions: %v", err)
+	}
+
+	if err := vfs.Chmod("/home", 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jan.FindItem("/home/jan.txt"); err != ErrPermissionDenied {
+		t.Errorf("FindItem without search permission: got %v, want ErrPermissionDenied", err)
+	}
+	if err := jan.DeleteItem("/home/jan.txt"); err != ErrPermissionDenied {
+		t.Errorf("DeleteItem without write permission: got %v, want ErrPermissionDenied", err)
+	}
+	if err := vfs.DeleteItem("/home/jan.txt"); err != nil {
+		t.Errorf("DeleteItem as root: %v", err)
+	}
+}